@@ -82,6 +82,8 @@ aws cloudformation deploy \
     ProjectName="$PROJECT_NAME" \
     DeployBucket="$S3_BUCKET" \
     OpenAIApiKey="$OPENAI_API_KEY" \
+    AdminSecret="${ADMIN_SECRET:-}" \
+    InviteSecret="${INVITE_SECRET:-}" \
   --capabilities CAPABILITY_NAMED_IAM \
   --region "$AWS_REGION" \
   --no-fail-on-empty-changeset
@@ -0,0 +1,339 @@
+// admin.go - 管理API（ルーム一覧・調査・クローズ・古いルームの一括削除）
+// 全ての操作は管理者シークレットで保護され、監査ログを出力する
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// requireAdmin - 管理者シークレットを検証
+// ADMIN_SECRETが未設定の場合は管理API自体を無効とする
+func requireAdmin(args map[string]interface{}) error {
+	secret := os.Getenv("ADMIN_SECRET")
+	if secret == "" {
+		return fmt.Errorf("管理APIは無効化されています")
+	}
+
+	given, _ := args["adminSecret"].(string)
+	if subtle.ConstantTimeCompare([]byte(given), []byte(secret)) != 1 {
+		return fmt.Errorf("管理者シークレットが正しくありません")
+	}
+	return nil
+}
+
+// auditLog - 管理操作の監査ログを出力
+// adminSecretはログに残さない（redactArgsで伏せる）
+func auditLog(operation string, args map[string]interface{}, result interface{}, opErr error) {
+	entry := map[string]interface{}{
+		"operation": operation,
+		"at":        time.Now().UTC().Format(time.RFC3339),
+		"arguments": redactArgs(args),
+	}
+
+	if opErr != nil {
+		entry["error"] = opErr.Error()
+	} else {
+		entry["result"] = result
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("[AUDIT] %s (監査ログのマーシャルに失敗: %v)", operation, err)
+		return
+	}
+	log.Printf("[AUDIT] %s", data)
+}
+
+// requireRoomIDArg - roomId引数を取得（未指定・文字列以外はエラー）
+func requireRoomIDArg(args map[string]interface{}) (string, error) {
+	roomID, ok := args["roomId"].(string)
+	if !ok || roomID == "" {
+		return "", fmt.Errorf("roomIdを指定してください")
+	}
+	return roomID, nil
+}
+
+// isDryRun - dryRun引数を取得（省略時はfalse）
+func isDryRun(args map[string]interface{}) bool {
+	dryRun, _ := args["dryRun"].(bool)
+	return dryRun
+}
+
+// listActiveRooms - クローズされていないルームの概要一覧を取得（管理者のみ）
+func listActiveRooms(ctx context.Context, args map[string]interface{}) (result []AdminRoomSummary, err error) {
+	if err := requireAdmin(args); err != nil {
+		auditLog("listActiveRooms", args, nil, err)
+		return nil, err
+	}
+	defer func() { auditLog("listActiveRooms", args, len(result), err) }()
+
+	rooms, err := scanActiveRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// ルームごとにクエリせず、プレイヤーのテーブルを1回スキャンしてルームごとに数える
+	playerItems, err := scanAllItems(ctx, playerTable)
+	if err != nil {
+		return nil, fmt.Errorf("プレイヤーのスキャンに失敗: %w", err)
+	}
+	var players []Player
+	if err := attributevalue.UnmarshalListOfMaps(playerItems, &players); err != nil {
+		return nil, fmt.Errorf("プレイヤーのアンマーシャルに失敗: %w", err)
+	}
+	playerCounts := make(map[string]int)
+	for _, p := range players {
+		playerCounts[p.RoomID]++
+	}
+
+	summaries := []AdminRoomSummary{}
+	for _, room := range rooms {
+		summaries = append(summaries, AdminRoomSummary{
+			RoomID:      room.RoomID,
+			RoomCode:    room.RoomCode,
+			State:       room.State,
+			PlayerCount: playerCounts[room.RoomID],
+			CreatedAt:   room.CreatedAt,
+			UpdatedAt:   room.UpdatedAt,
+		})
+	}
+
+	return summaries, nil
+}
+
+// inspectRoom - ルームの詳細を取得（管理者のみ）
+// プレイヤー・回答を含むルーム全体を返す（お題プールはgetRoomと同じく文のみで、カテゴリ・想定回答は含まない）
+func inspectRoom(ctx context.Context, args map[string]interface{}) (room *Room, err error) {
+	if err := requireAdmin(args); err != nil {
+		auditLog("inspectRoom", args, nil, err)
+		return nil, err
+	}
+	defer func() { auditLog("inspectRoom", args, room != nil, err) }()
+
+	roomID, err := requireRoomIDArg(args)
+	if err != nil {
+		return nil, err
+	}
+	room, err = getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	return room, nil
+}
+
+// closeRoom - 指定したルームとそのプレイヤー・回答を削除（管理者のみ）
+func closeRoom(ctx context.Context, args map[string]interface{}) (result *AdminCleanupResult, err error) {
+	if err := requireAdmin(args); err != nil {
+		auditLog("closeRoom", args, nil, err)
+		return nil, err
+	}
+	defer func() { auditLog("closeRoom", args, result, err) }()
+
+	roomID, err := requireRoomIDArg(args)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := isDryRun(args)
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	counts, err := deleteRoomCascade(ctx, room, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AdminCleanupResult{
+		DryRun:        dryRun,
+		RoomIDs:       []string{roomID},
+		DeletedCounts: counts,
+	}, nil
+}
+
+// purgeRoomsOlderThan - 最終更新から指定時間が経過したルームを一括削除（管理者のみ）
+// durationはGoのtime.ParseDuration形式（例: "24h", "90m"）
+func purgeRoomsOlderThan(ctx context.Context, args map[string]interface{}) (result *AdminCleanupResult, err error) {
+	if err := requireAdmin(args); err != nil {
+		auditLog("purgeRoomsOlderThan", args, nil, err)
+		return nil, err
+	}
+	defer func() { auditLog("purgeRoomsOlderThan", args, result, err) }()
+
+	durationArg, ok := args["duration"].(string)
+	if !ok || durationArg == "" {
+		return nil, fmt.Errorf("durationを指定してください")
+	}
+	duration, err := time.ParseDuration(durationArg)
+	if err != nil {
+		return nil, fmt.Errorf("durationの形式が正しくありません: %w", err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("durationは正の値を指定してください")
+	}
+	dryRun := isDryRun(args)
+	cutoff := time.Now().UTC().Add(-duration)
+
+	rooms, err := scanRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &AdminCleanupResult{
+		DryRun:  dryRun,
+		RoomIDs: []string{},
+	}
+	for i := range rooms {
+		room := &rooms[i]
+		updatedAt, err := time.Parse(time.RFC3339, room.UpdatedAt)
+		if err != nil {
+			log.Printf("警告: updatedAtの解析に失敗 %s: %v", room.RoomID, err)
+			continue
+		}
+		if updatedAt.After(cutoff) {
+			continue
+		}
+
+		counts, err := deleteRoomCascade(ctx, room, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		result.RoomIDs = append(result.RoomIDs, room.RoomID)
+		result.DeletedCounts.Rooms += counts.Rooms
+		result.DeletedCounts.Players += counts.Players
+		result.DeletedCounts.Answers += counts.Answers
+	}
+
+	return result, nil
+}
+
+// scanRooms - ルームテーブルを全件スキャン
+func scanRooms(ctx context.Context) ([]Room, error) {
+	items, err := scanAllItems(ctx, roomTable)
+	if err != nil {
+		return nil, fmt.Errorf("ルームのスキャンに失敗: %w", err)
+	}
+
+	var rooms []Room
+	if err := attributevalue.UnmarshalListOfMaps(items, &rooms); err != nil {
+		return nil, fmt.Errorf("ルームのアンマーシャルに失敗: %w", err)
+	}
+
+	return rooms, nil
+}
+
+// scanActiveRooms - クローズされていないルームを全件取得
+func scanActiveRooms(ctx context.Context) ([]Room, error) {
+	var rooms []Room
+
+	paginator := dynamodb.NewScanPaginator(ddbClient, &dynamodb.ScanInput{
+		TableName:        aws.String(roomTable),
+		FilterExpression: aws.String("#state <> :closed"),
+		ExpressionAttributeNames: map[string]string{
+			"#state": "state",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":closed": &types.AttributeValueMemberS{Value: "CLOSED"},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ルームのスキャンに失敗: %w", err)
+		}
+		var pageRooms []Room
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageRooms); err != nil {
+			return nil, fmt.Errorf("ルームのアンマーシャルに失敗: %w", err)
+		}
+		rooms = append(rooms, pageRooms...)
+	}
+
+	return rooms, nil
+}
+
+// deleteRoomCascade - ルームと所属するプレイヤー・回答を削除
+// dryRunの場合は削除対象の件数のみを数える
+// 削除できなかったプレイヤー・回答がある場合は、再実行で削除できるようルームを残してエラーを返す
+func deleteRoomCascade(ctx context.Context, room *Room, dryRun bool) (DeletedCounts, error) {
+	counts := DeletedCounts{}
+	failedAnswers, failedPlayers := 0, 0
+
+	answers, err := listAnswers(ctx, map[string]interface{}{"roomId": room.RoomID})
+	if err != nil {
+		return counts, err
+	}
+	for _, answer := range answers {
+		if !dryRun {
+			_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(answerTable),
+				Key: map[string]types.AttributeValue{
+					"answerId": &types.AttributeValueMemberS{Value: answer.AnswerID},
+				},
+			})
+			if err != nil {
+				log.Printf("警告: 回答の削除に失敗 %s: %v", answer.AnswerID, err)
+				failedAnswers++
+				continue
+			}
+		}
+		counts.Answers++
+	}
+
+	players, err := listPlayers(ctx, map[string]interface{}{"roomId": room.RoomID})
+	if err != nil {
+		return counts, err
+	}
+	for _, player := range players {
+		if !dryRun {
+			_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(playerTable),
+				Key: map[string]types.AttributeValue{
+					"playerId": &types.AttributeValueMemberS{Value: player.PlayerID},
+				},
+			})
+			if err != nil {
+				log.Printf("警告: プレイヤーの削除に失敗 %s: %v", player.PlayerID, err)
+				failedPlayers++
+				continue
+			}
+		}
+		counts.Players++
+	}
+
+	if failedAnswers > 0 || failedPlayers > 0 {
+		return counts, fmt.Errorf("ルーム%sの回答%d件・プレイヤー%d件を削除できなかったため、ルームは削除していません（再実行してください）", room.RoomID, failedAnswers, failedPlayers)
+	}
+
+	if !dryRun {
+		_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(roomTable),
+			Key: map[string]types.AttributeValue{
+				"roomId": &types.AttributeValueMemberS{Value: room.RoomID},
+			},
+		})
+		if err != nil {
+			return counts, fmt.Errorf("ルームの削除に失敗: %w", err)
+		}
+	}
+	counts.Rooms++
+
+	return counts, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAdminArgumentValidation(t *testing.T) {
+	t.Setenv("ADMIN_SECRET", "secret")
+	ctx := context.Background()
+
+	// いずれもDBにアクセスする前にエラーになる（パニックしない）
+	tests := []struct {
+		name    string
+		call    func(args map[string]interface{}) error
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"inspectRoomのroomIdなし", func(a map[string]interface{}) error { _, err := inspectRoom(ctx, a); return err }, map[string]interface{}{"adminSecret": "secret"}, "roomId"},
+		{"closeRoomのroomIdが文字列以外", func(a map[string]interface{}) error { _, err := closeRoom(ctx, a); return err }, map[string]interface{}{"adminSecret": "secret", "roomId": 1.0}, "roomId"},
+		{"purgeRoomsOlderThanのdurationなし", func(a map[string]interface{}) error { _, err := purgeRoomsOlderThan(ctx, a); return err }, map[string]interface{}{"adminSecret": "secret"}, "duration"},
+		{"purgeRoomsOlderThanのdurationが不正", func(a map[string]interface{}) error { _, err := purgeRoomsOlderThan(ctx, a); return err }, map[string]interface{}{"adminSecret": "secret", "duration": "1日"}, "duration"},
+		{"purgeRoomsOlderThanのdurationが負", func(a map[string]interface{}) error { _, err := purgeRoomsOlderThan(ctx, a); return err }, map[string]interface{}{"adminSecret": "secret", "duration": "-1h"}, "duration"},
+		{"誤った管理者シークレット", func(a map[string]interface{}) error { _, err := closeRoom(ctx, a); return err }, map[string]interface{}{"adminSecret": "wrong", "roomId": "room-1"}, "管理者シークレット"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q を含むエラー", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// breaker.go - OpenAI API呼び出しのリトライ（指数バックオフ）とサーキットブレーカー
+// ブレーカーの状態はLambdaのウォームスタート間で共有され、障害中はAPIを呼ばずにすぐフォールバックへ切り替える
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	llmMaxAttempts      = 3                      // 1回の呼び出しあたりの最大試行回数
+	llmBackoffBase      = 500 * time.Millisecond // バックオフの初期値（試行ごとに2倍）
+	llmBackoffMax       = 4 * time.Second        // バックオフの上限
+	llmDeadlineMargin   = 2 * time.Second        // 呼び出し元の締め切りより前に打ち切る余裕（フォールバックの時間を残す）
+	llmDefaultTimeout   = 60 * time.Second       // 呼び出し元に締め切りがない場合のタイムアウト
+	breakerThreshold    = 5                      // ブレーカーを開く連続失敗回数
+	breakerOpenDuration = 30 * time.Second       // ブレーカーを開いておく時間（経過後に1回だけ試す）
+)
+
+// errCircuitOpen - ブレーカーが開いているためAPIを呼ばなかった
+var errCircuitOpen = errors.New("OpenAI APIは一時的に利用を停止しています")
+
+// errOutputTruncated - 出力が上限のトークン数で打ち切られた（構造化出力のJSONが途中で終わっているため使えない）
+// 同じリクエストを再試行しても打ち切られるため再試行せず、APIは応答しているためブレーカーの失敗にも数えない
+var errOutputTruncated = errors.New("OpenAI APIの出力が上限のトークン数で打ち切られました")
+
+// retryableError - 再試行で回復する可能性のあるエラー（429・5xx・通信エラー）
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // Retry-Afterで指定された待ち時間（指定がない場合は0）
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// circuitBreaker - 連続失敗回数に応じてAPI呼び出しを止める
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int       // 連続失敗回数
+	openUntil time.Time // この時刻まではAPIを呼ばない
+	probing   bool      // 開いた後の試行中（同時に1回だけ試す）
+}
+
+// openAIBreaker - OpenAI API用のブレーカー
+var openAIBreaker = &circuitBreaker{}
+
+// allow - APIを呼んでよいか
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record - 呼び出し結果を記録（成功で閉じ、連続失敗が閾値に達したら開く）
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil || errors.Is(err, errOutputTruncated) {
+		if b.failures >= breakerThreshold {
+			log.Println("OpenAI APIの呼び出しが回復しました")
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerOpenDuration)
+		log.Printf("警告: OpenAI APIの失敗が%d回続いたため%sの間呼び出しを停止します", b.failures, breakerOpenDuration)
+	}
+}
+
+// withLLMRetry - ブレーカーを確認し、再試行可能なエラーの間はバックオフしながらcallを繰り返す
+// 各試行には呼び出し元の締め切りから余裕を引いた締め切りを設定する
+func withLLMRetry(ctx context.Context, call func(ctx context.Context) (string, error)) (string, error) {
+	if !openAIBreaker.allow() {
+		return "", errCircuitOpen
+	}
+
+	ctx, cancel := llmContext(ctx)
+	defer cancel()
+
+	var lastErr error
+	for attempt := 1; attempt <= llmMaxAttempts; attempt++ {
+		content, err := call(ctx)
+		if err == nil {
+			openAIBreaker.record(nil)
+			return content, nil
+		}
+		lastErr = err
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt == llmMaxAttempts {
+			break
+		}
+
+		wait := backoff(attempt, retryable.retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			log.Printf("警告: 締め切りまでに再試行できないため打ち切ります: %v", err)
+			break
+		}
+		log.Printf("警告: OpenAI APIの呼び出しに失敗（%d回目）、%s後に再試行: %v", attempt, wait, err)
+
+		if err := sleepContext(ctx, wait); err != nil {
+			lastErr = err
+			break
+		}
+	}
+
+	openAIBreaker.record(lastErr)
+	return "", lastErr
+}
+
+// sleepContext - 指定時間待つ（コンテキストが終了した場合はそのエラーを返す）
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// llmContext - API呼び出し用の締め切りを設定したコンテキスト
+func llmContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(ctx, deadline.Add(-llmDeadlineMargin))
+	}
+	return context.WithTimeout(ctx, llmDefaultTimeout)
+}
+
+// backoff - attempt回目の失敗後の待ち時間（フルジッター付き指数バックオフ）
+// Retry-Afterが指定された場合はその時間を待つ（締め切りを超える場合はwithLLMRetryが打ち切る）
+func backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	ceiling := llmBackoffBase << (attempt - 1)
+	if ceiling > llmBackoffMax {
+		ceiling = llmBackoffMax
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)) + 1)
+}
+
+// isRetryableStatus - 再試行するHTTPステータス
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter - Retry-Afterヘッダー（秒数またはHTTP日付）を待ち時間に変換
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// statusError - HTTPステータスのエラーを作成（再試行可能なステータスはretryableErrorで包む）
+func statusError(resp *http.Response, body []byte) error {
+	err := fmt.Errorf("OpenAI APIエラー: %d - %s", resp.StatusCode, string(body))
+	if !isRetryableStatus(resp.StatusCode) {
+		return err
+	}
+	return &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+}
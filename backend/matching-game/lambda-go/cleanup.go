@@ -0,0 +1,222 @@
+// cleanup.go - TTL管理と孤立データの掃除
+// プレイヤー・回答のTTLはルームのTTLに揃え、ルームに動きがあるたびに延長する
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	roomTTLSeconds         = 86400 // ルームの有効期間（最後の活動から24時間）
+	roomTTLExtendThreshold = 43200 // 残り時間がこれを下回ったら延長する（12時間）
+	closedRoomTTLSeconds   = 3600  // クローズ後にアーカイブとして残す時間（1時間）
+)
+
+// newRoomTTL - 現在時刻から計算したルームのTTL（UNIX秒）
+func newRoomTTL() int64 {
+	return time.Now().Unix() + roomTTLSeconds
+}
+
+// extendRoomTTL - ルームに動きがあった際にTTLを延長
+// 書き込み量を抑えるため、残り時間が閾値を下回った場合のみルーム・プレイヤー・回答をまとめて更新する
+func extendRoomTTL(ctx context.Context, room *Room) {
+	if room.TTL-time.Now().Unix() > roomTTLExtendThreshold {
+		return
+	}
+
+	ttl := newRoomTTL()
+	log.Printf("TTLを延長: roomId=%s, ttl=%d", room.RoomID, ttl)
+
+	if err := setItemTTL(ctx, roomTable, "roomId", room.RoomID, ttl); err != nil {
+		log.Printf("警告: ルームのTTL延長に失敗 %s: %v", room.RoomID, err)
+		return
+	}
+	room.TTL = ttl
+
+	players, err := listPlayers(ctx, map[string]interface{}{"roomId": room.RoomID})
+	if err != nil {
+		log.Printf("警告: プレイヤーの取得に失敗: %v", err)
+	}
+	for _, player := range players {
+		if err := setItemTTL(ctx, playerTable, "playerId", player.PlayerID, ttl); err != nil {
+			log.Printf("警告: プレイヤーのTTL延長に失敗 %s: %v", player.PlayerID, err)
+		}
+	}
+
+	answers, err := listAnswers(ctx, map[string]interface{}{"roomId": room.RoomID})
+	if err != nil {
+		log.Printf("警告: 回答の取得に失敗: %v", err)
+	}
+	for _, answer := range answers {
+		if err := setItemTTL(ctx, answerTable, "answerId", answer.AnswerID, ttl); err != nil {
+			log.Printf("警告: 回答のTTL延長に失敗 %s: %v", answer.AnswerID, err)
+		}
+	}
+}
+
+// setItemTTL - 指定したアイテムのttl属性を更新
+func setItemTTL(ctx context.Context, table, keyName, keyValue string, ttl int64) error {
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key: map[string]types.AttributeValue{
+			keyName: &types.AttributeValueMemberS{Value: keyValue},
+		},
+		UpdateExpression: aws.String("SET #ttl = :ttl"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(ttl, 10)},
+		},
+	})
+	return err
+}
+
+// cleanupOrphans - 管理APIから孤立データの掃除を実行（管理者のみ）
+func cleanupOrphans(ctx context.Context, args map[string]interface{}) (report *OrphanCleanupReport, err error) {
+	if err := requireAdmin(args); err != nil {
+		auditLog("cleanupOrphans", args, nil, err)
+		return nil, err
+	}
+	defer func() { auditLog("cleanupOrphans", args, report, err) }()
+
+	return runOrphanCleanup(ctx, isDryRun(args))
+}
+
+// scheduledCleanup - EventBridgeの定期実行から呼ばれる掃除処理
+// AppSyncスキーマには存在しないフィールドのため、Lambdaの直接呼び出しでのみ実行される
+func scheduledCleanup(ctx context.Context) (*OrphanCleanupReport, error) {
+	report, err := runOrphanCleanup(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("定期クリーンアップ完了: %+v", *report)
+	return report, nil
+}
+
+// runOrphanCleanup - ルームが既に存在しないプレイヤー・回答を削除
+// dryRunの場合は削除対象の件数のみを数える
+func runOrphanCleanup(ctx context.Context, dryRun bool) (*OrphanCleanupReport, error) {
+	report := &OrphanCleanupReport{
+		DryRun:        dryRun,
+		OrphanRoomIDs: []string{},
+	}
+
+	// ルームの存在確認結果をキャッシュ（同じルームを何度も問い合わせない）
+	exists := make(map[string]bool)
+	roomExists := func(roomID string) (bool, error) {
+		if v, ok := exists[roomID]; ok {
+			return v, nil
+		}
+		result, err := ddbClient.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(roomTable),
+			Key: map[string]types.AttributeValue{
+				"roomId": &types.AttributeValueMemberS{Value: roomID},
+			},
+			ProjectionExpression: aws.String("roomId"),
+		})
+		if err != nil {
+			return false, fmt.Errorf("ルームの取得に失敗: %w", err)
+		}
+		exists[roomID] = result.Item != nil
+		if result.Item == nil {
+			report.OrphanRoomIDs = append(report.OrphanRoomIDs, roomID)
+		}
+		return exists[roomID], nil
+	}
+
+	// 孤立した回答を削除
+	answerItems, err := scanAllItems(ctx, answerTable)
+	if err != nil {
+		return nil, fmt.Errorf("回答のスキャンに失敗: %w", err)
+	}
+	report.ScannedAnswers = len(answerItems)
+	for _, item := range answerItems {
+		var answer Answer
+		if err := attributevalue.UnmarshalMap(item, &answer); err != nil {
+			continue
+		}
+		ok, err := roomExists(answer.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		if !dryRun {
+			_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(answerTable),
+				Key: map[string]types.AttributeValue{
+					"answerId": &types.AttributeValueMemberS{Value: answer.AnswerID},
+				},
+			})
+			if err != nil {
+				log.Printf("警告: 回答の削除に失敗 %s: %v", answer.AnswerID, err)
+				continue
+			}
+		}
+		report.DeletedCounts.Answers++
+	}
+
+	// 孤立したプレイヤーを削除
+	playerItems, err := scanAllItems(ctx, playerTable)
+	if err != nil {
+		return nil, fmt.Errorf("プレイヤーのスキャンに失敗: %w", err)
+	}
+	report.ScannedPlayers = len(playerItems)
+	for _, item := range playerItems {
+		var player Player
+		if err := attributevalue.UnmarshalMap(item, &player); err != nil {
+			continue
+		}
+		ok, err := roomExists(player.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		if !dryRun {
+			_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(playerTable),
+				Key: map[string]types.AttributeValue{
+					"playerId": &types.AttributeValueMemberS{Value: player.PlayerID},
+				},
+			})
+			if err != nil {
+				log.Printf("警告: プレイヤーの削除に失敗 %s: %v", player.PlayerID, err)
+				continue
+			}
+		}
+		report.DeletedCounts.Players++
+	}
+
+	return report, nil
+}
+
+// scanAllItems - テーブルを全件スキャン（ページネーション対応）
+func scanAllItems(ctx context.Context, table string) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+
+	paginator := dynamodb.NewScanPaginator(ddbClient, &dynamodb.ScanInput{
+		TableName: aws.String(table),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+
+	return items, nil
+}
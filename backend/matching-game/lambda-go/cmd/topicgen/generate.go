@@ -0,0 +1,202 @@
+// generate.go - お題のバッチ生成（generateサブコマンド）
+// 各バッチは前のバッチまでに採用したお題・既存のお題を使用済みとしてプロンプトに渡し、同じお題を繰り返させない
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+// promptData - プロンプトのテンプレート（-prompt）に渡す値
+// テンプレートの本文はシステムプロンプト、{{define "user"}}〜{{end}}で定義した部分はユーザーメッセージになる
+type promptData struct {
+	Categories string // カテゴリ配分の指示（topicgen.CategoryText）
+	Avoid      string // 使用済みお題の指示（topicgen.AvoidText、使用済みがない場合は空文字）
+	Count      int    // 1回の生成で出力させるお題の数
+}
+
+// runGenerate - お題を生成してデッキファイルに書き出す
+func runGenerate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	batches := fs.Int("batches", 1, "生成を呼び出す回数")
+	count := fs.Int("count", topicgen.DefaultTopicCount, "1回の生成で出力させるお題の数（多いと出力が上限のトークン数で打ち切られる）")
+	categoriesFlag := fs.String("categories", "", "出題するカテゴリ（カンマ区切り、空は全カテゴリを既定の配分で）")
+	promptPath := fs.String("prompt", "", "プロンプトのテンプレート（text/template、{{.Categories}} {{.Avoid}} {{.Count}}を使える。本文がシステムプロンプト、{{define \"user\"}}〜{{end}}がユーザーメッセージ（省略時はLambdaと同じ）、空はLambdaと同じプロンプト）")
+	providerName := fs.String("provider", "openai", "LLMのプロバイダー（openai, anthropic）")
+	model := fs.String("model", "", "モデル名（空はプロバイダーの既定）")
+	baseURL := fs.String("base-url", "", "APIのベースURL（OpenAI互換のサーバーを使う場合）")
+	apiKeyEnv := fs.String("api-key-env", "", "APIキーを読む環境変数（空はOPENAI_API_KEY・ANTHROPIC_API_KEY）")
+	temperature := fs.Float64("temperature", 0.9, "生成の温度")
+	existing := fs.String("existing", "", "重複を除く既存のデッキファイル（JSON・CSV、カンマ区切り）")
+	bankTable := fs.String("bank-table", "", "重複を除くお題バンクのDynamoDBテーブル名")
+	out := fs.String("out", "-", "書き出すデッキファイル（「-」は標準出力）")
+	name := fs.String("name", "", "デッキ名")
+	description := fs.String("description", "", "デッキの説明")
+	tags := fs.String("tags", "", "生成したお題に付けるタグ（カンマ区切り）")
+	language := fs.String("language", topicgen.DefaultDeckLanguage, "お題の言語")
+	fs.Parse(args)
+
+	if *batches < 1 {
+		return fmt.Errorf("-batchesは1以上にしてください")
+	}
+	if *count < 1 {
+		return fmt.Errorf("-countは1以上にしてください")
+	}
+	categories := splitList(*categoriesFlag)
+	for _, c := range categories {
+		if !topicgen.IsCategory(c) {
+			return fmt.Errorf("不明なカテゴリ: %s", c)
+		}
+	}
+
+	var tmpl *template.Template
+	if *promptPath != "" {
+		text, err := os.ReadFile(*promptPath)
+		if err != nil {
+			return fmt.Errorf("プロンプトの読み込みに失敗: %w", err)
+		}
+		tmpl, err = template.New("prompt").Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			return fmt.Errorf("プロンプトの解析に失敗: %w", err)
+		}
+	}
+
+	llm, err := newProvider(*providerName, *model, *baseURL, *apiKeyEnv, *temperature)
+	if err != nil {
+		return err
+	}
+
+	// 重複を除くお題（既存のデッキ・バンク）を集める
+	var used []string
+	for _, path := range splitList(*existing) {
+		file, err := readDeckFile(path)
+		if err != nil {
+			return err
+		}
+		for _, t := range file.Topics {
+			used = append(used, strings.TrimSpace(t.Topic))
+		}
+	}
+	if *bankTable != "" {
+		client, err := newDynamoDBClient(ctx)
+		if err != nil {
+			return err
+		}
+		texts, err := scanBankTexts(ctx, client, *bankTable)
+		if err != nil {
+			return err
+		}
+		used = append(used, texts...)
+	}
+	usedMap := make(map[string]bool)
+	for _, t := range used {
+		usedMap[t] = true
+	}
+	log.Printf("既存のお題: %d個", len(usedMap))
+
+	// バッチごとに生成し、採用したお題を次のバッチの使用済みに加える
+	quotas := topicgen.NewQuotas(categories, *count)
+	var generated []topicgen.Topic
+	for i := 1; i <= *batches; i++ {
+		system, user, err := buildPrompt(tmpl, used, quotas)
+		if err != nil {
+			return err
+		}
+
+		content, err := llm.complete(ctx, system, user, topicgen.ResponseSchema(quotas))
+		if errors.Is(err, errTruncated) {
+			// -countが出力の上限に対して多すぎるため、次のバッチも同じく打ち切られる
+			return fmt.Errorf("%d回目の生成: %w。-count（現在%d）を減らしてください", i, err, quotas.Total())
+		}
+		if err != nil {
+			log.Printf("警告: %d回目の生成に失敗: %v", i, err)
+			continue
+		}
+
+		var output struct {
+			Topics []topicgen.Topic `json:"topics"`
+		}
+		if err := json.Unmarshal([]byte(content), &output); err != nil {
+			log.Printf("警告: %d回目の生成結果のJSONの解析に失敗: %v", i, err)
+			continue
+		}
+
+		accepted, rejected := topicgen.Accept(output.Topics, categories, usedMap)
+		for _, err := range rejected {
+			log.Printf("除外: %v", err)
+		}
+		for _, warning := range topicgen.QuotaShortfalls(accepted, quotas) {
+			log.Printf("警告: %s", warning)
+		}
+		log.Printf("%d/%d回目: 出力=%d, 採用=%d, 不正=%d, 重複=%d", i, *batches,
+			len(output.Topics), len(accepted), len(rejected), len(output.Topics)-len(accepted)-len(rejected))
+
+		for _, t := range accepted {
+			usedMap[t.Text] = true
+			used = append(used, t.Text)
+		}
+		generated = append(generated, accepted...)
+	}
+	if len(generated) == 0 {
+		return fmt.Errorf("有効なお題が生成されませんでした")
+	}
+
+	// デッキの形式に変換し、importTopicDeckと同じ検証を通す
+	rows := make([]topicgen.DeckTopic, 0, len(generated))
+	for _, t := range generated {
+		rows = append(rows, topicgen.DeckTopic{
+			Topic:          t.Text,
+			Category:       t.Category,
+			Tags:           splitList(*tags),
+			Language:       *language,
+			ExampleAnswers: t.ExampleAnswers,
+		})
+	}
+	topics, skipped := topicgen.ValidateDeckTopics(rows)
+	for _, reason := range skipped {
+		log.Printf("除外: %s", reason)
+	}
+
+	file := &topicgen.DeckFile{Name: *name, Description: *description, Topics: topics}
+	if err := writeDeckFile(*out, file); err != nil {
+		return err
+	}
+	log.Printf("%d個のお題を書き出しました: %s", len(topics), *out)
+	return nil
+}
+
+// buildPrompt - システムプロンプトとユーザーメッセージを作成（テンプレートの指定がない部分はLambdaと同じプロンプト）
+func buildPrompt(tmpl *template.Template, used []string, quotas topicgen.Quotas) (string, string, error) {
+	if tmpl == nil {
+		return topicgen.SystemPrompt(used, quotas), topicgen.UserPrompt(quotas.Total()), nil
+	}
+
+	data := promptData{
+		Categories: topicgen.CategoryText(quotas),
+		Avoid:      topicgen.AvoidText(used),
+		Count:      quotas.Total(),
+	}
+	var system strings.Builder
+	if err := tmpl.Execute(&system, data); err != nil {
+		return "", "", fmt.Errorf("プロンプトの作成に失敗: %w", err)
+	}
+
+	user := topicgen.UserPrompt(quotas.Total())
+	if t := tmpl.Lookup("user"); t != nil {
+		var sb strings.Builder
+		if err := t.Execute(&sb, data); err != nil {
+			return "", "", fmt.Errorf("ユーザーメッセージの作成に失敗: %w", err)
+		}
+		user = sb.String()
+	}
+	return strings.TrimSpace(system.String()), strings.TrimSpace(user), nil
+}
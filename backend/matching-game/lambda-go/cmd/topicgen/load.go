@@ -0,0 +1,125 @@
+// load.go - 精査したお題のお題バンクへの追加（loadサブコマンド）
+// バンクのお題はルームのカテゴリ指定・難易度の見積もりに使うため、生成したお題と同じ検証（定義済みのカテゴリ・想定回答1〜3個）を通ったものだけを追加する
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const (
+	batchWriteSize      = 25 // BatchWriteItemの1回あたりの上限
+	maxUnprocessedRetry = 5  // 書き込めなかった項目を再送する回数
+)
+
+// bankItem - お題バンクの項目（Lambdaのお題バンクと同じ属性）
+type bankItem struct {
+	Text           string   `dynamodbav:"text"`
+	Category       string   `dynamodbav:"category"`
+	ExampleAnswers []string `dynamodbav:"exampleAnswers"`
+	CreatedAt      string   `dynamodbav:"createdAt"`
+}
+
+// runLoad - デッキファイルのお題をお題バンクに追加（バンクにあるお題は上書きしない）
+func runLoad(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	in := fs.String("in", "", "追加するデッキファイル（JSON・CSV）")
+	bankTable := fs.String("bank-table", "", "お題バンクのDynamoDBテーブル名")
+	dryRun := fs.Bool("dry-run", false, "検証のみ行い、書き込まない")
+	fs.Parse(args)
+
+	if *in == "" || *bankTable == "" {
+		return fmt.Errorf("-inと-bank-tableを指定してください")
+	}
+
+	file, err := readDeckFile(*in)
+	if err != nil {
+		return err
+	}
+
+	client, err := newDynamoDBClient(ctx)
+	if err != nil {
+		return err
+	}
+	texts, err := scanBankTexts(ctx, client, *bankTable)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool)
+	for _, t := range texts {
+		exists[t] = true
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	var items []bankItem
+	duplicates := 0
+	for i, row := range file.Topics {
+		t, err := topicgen.Validate(topicgen.Topic{Text: row.Topic, Category: row.Category, ExampleAnswers: row.ExampleAnswers}, nil)
+		if err != nil {
+			log.Printf("除外: %d行目: %v", i+1, err)
+			continue
+		}
+		if exists[t.Text] {
+			duplicates++
+			continue
+		}
+		exists[t.Text] = true
+		items = append(items, bankItem{Text: t.Text, Category: t.Category, ExampleAnswers: t.ExampleAnswers, CreatedAt: now})
+	}
+	log.Printf("追加するお題: %d個（ファイル=%d, 重複=%d）", len(items), len(file.Topics), duplicates)
+
+	if *dryRun || len(items) == 0 {
+		return nil
+	}
+	if err := writeBankItems(ctx, client, *bankTable, items); err != nil {
+		return err
+	}
+	log.Printf("お題バンクに%d個のお題を追加しました", len(items))
+	return nil
+}
+
+// writeBankItems - お題をバンクに書き込む（書き込めなかった項目は待ってから再送する）
+func writeBankItems(ctx context.Context, client *dynamodb.Client, table string, items []bankItem) error {
+	for start := 0; start < len(items); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		var requests []types.WriteRequest
+		for _, item := range items[start:end] {
+			av, err := attributevalue.MarshalMap(item)
+			if err != nil {
+				return fmt.Errorf("お題のマーシャルに失敗: %w", err)
+			}
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+		}
+
+		for attempt := 0; len(requests) > 0; attempt++ {
+			if attempt == maxUnprocessedRetry {
+				return fmt.Errorf("お題バンクに追加できなかったお題が%d個あります", len(requests))
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * time.Second)
+			}
+
+			result, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]types.WriteRequest{table: requests},
+			})
+			if err != nil {
+				return fmt.Errorf("お題バンクへの追加に失敗: %w", err)
+			}
+			requests = result.UnprocessedItems[table]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// cmd/topicgen - お題をオフラインで事前生成・精査するCLI
+// generate: LLMでお題をバッチ生成し、既存のバンク・デッキと重複しないものをデッキファイル（JSON）に書き出す
+// load: 精査したデッキファイルのお題をお題バンク（DynamoDB）に追加する
+// お題の検証ルール・プロンプト・ファイル形式はLambdaと共通（topicgenパッケージ）で、書き出したファイルはimportTopicDeckでデッキとしても取り込める
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const usage = `使い方:
+  topicgen generate [フラグ]  お題を生成してデッキファイルに書き出す
+  topicgen load [フラグ]      デッキファイルのお題をお題バンクに追加する
+
+各サブコマンドのフラグは topicgen <サブコマンド> -h で確認できます`
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = runGenerate(ctx, os.Args[2:])
+	case "load":
+		err = runLoad(ctx, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("エラー: %v", err)
+	}
+}
+
+// splitList - カンマ区切りのフラグの値をリストに変換（空の要素は除く）
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// readDeckFile - デッキファイルを読み込む（拡張子が.csvの場合はCSV、それ以外はJSON）
+func readDeckFile(path string) (*topicgen.DeckFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%sの読み込みに失敗: %w", path, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		topics, err := topicgen.ParseDeckCSV(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &topicgen.DeckFile{Topics: topics}, nil
+	}
+
+	file, err := topicgen.ParseDeckJSON(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// writeDeckFile - デッキファイルをJSONで書き出す（pathが「-」の場合は標準出力）
+func writeDeckFile(path string, file *topicgen.DeckFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("デッキのマーシャルに失敗: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("%sの書き込みに失敗: %w", path, err)
+	}
+	return nil
+}
+
+// newDynamoDBClient - 環境の認証情報（AWS_PROFILE等）でDynamoDBクライアントを作成
+func newDynamoDBClient(ctx context.Context) (*dynamodb.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SDK設定の読み込みに失敗: %w", err)
+	}
+	return dynamodb.NewFromConfig(cfg), nil
+}
+
+// scanBankTexts - お題バンクの全お題の文を取得
+func scanBankTexts(ctx context.Context, client *dynamodb.Client, table string) ([]string, error) {
+	var texts []string
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:            aws.String(table),
+		ProjectionExpression: aws.String("#text"),
+		ExpressionAttributeNames: map[string]string{
+			"#text": "text",
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("お題バンクのスキャンに失敗: %w", err)
+		}
+		for _, item := range page.Items {
+			if v, ok := item["text"].(*types.AttributeValueMemberS); ok {
+				texts = append(texts, v.Value)
+			}
+		}
+	}
+
+	return texts, nil
+}
@@ -0,0 +1,230 @@
+// provider.go - LLMプロバイダーの呼び出し（OpenAI互換のChat Completions API・Anthropic Messages API）
+// どちらもお題のJSONスキーマで構造化出力させ、スキーマに沿ったJSONの文字列を返す
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	requestTimeout = 5 * time.Minute // 1回の生成の待ち時間の上限（100問以上の出力には数分かかることがある）
+	maxAttempts    = 3               // 429・5xx・通信エラーの場合の試行回数
+	maxTokens      = 16000           // 出力トークンの上限
+
+	anthropicVersion = "2023-06-01" // Anthropic APIのバージョン
+)
+
+// errTruncated - 出力が上限のトークン数で打ち切られた（JSONが不完全なため使えない）
+var errTruncated = errors.New("出力が上限のトークン数で打ち切られました")
+
+// provider - お題を生成するLLMプロバイダー
+type provider interface {
+	// complete - システムプロンプト・ユーザーメッセージからschemaに沿ったJSONを生成
+	complete(ctx context.Context, system, user string, schema map[string]interface{}) (string, error)
+}
+
+// newProvider - プロバイダー名からプロバイダーを作成（モデル・ベースURL・APIキーの環境変数は省略時にプロバイダーの既定を使う）
+func newProvider(name, model, baseURL, apiKeyEnv string, temperature float64) (provider, error) {
+	switch name {
+	case "openai":
+		if model == "" {
+			model = "gpt-4o-mini"
+		}
+		if apiKeyEnv == "" {
+			apiKeyEnv = "OPENAI_API_KEY"
+		}
+		apiKey := os.Getenv(apiKeyEnv)
+		// OpenAI互換のローカルサーバーはAPIキーが不要な場合がある
+		if apiKey == "" && baseURL == "" {
+			return nil, fmt.Errorf("%sが設定されていません", apiKeyEnv)
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		return &openAIProvider{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: apiKey, model: model, temperature: temperature}, nil
+
+	case "anthropic":
+		if model == "" {
+			model = "claude-sonnet-4-5"
+		}
+		if apiKeyEnv == "" {
+			apiKeyEnv = "ANTHROPIC_API_KEY"
+		}
+		apiKey := os.Getenv(apiKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("%sが設定されていません", apiKeyEnv)
+		}
+		if baseURL == "" {
+			baseURL = "https://api.anthropic.com/v1"
+		}
+		return &anthropicProvider{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: apiKey, model: model, temperature: temperature}, nil
+	}
+	return nil, fmt.Errorf("不明なプロバイダー: %s（openai, anthropicのいずれか）", name)
+}
+
+// openAIProvider - OpenAI互換のChat Completions API（json_schemaの構造化出力）
+type openAIProvider struct {
+	baseURL     string
+	apiKey      string
+	model       string
+	temperature float64
+}
+
+func (p *openAIProvider) complete(ctx context.Context, system, user string, schema map[string]interface{}) (string, error) {
+	reqBody := map[string]interface{}{
+		"model": p.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"temperature": p.temperature,
+		"max_tokens":  maxTokens,
+		"response_format": map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "topics",
+				"strict": true,
+				"schema": schema,
+			},
+		},
+	}
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	body, err := postJSON(ctx, p.baseURL+"/chat/completions", headers, reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("レスポンスのデコードに失敗: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("レスポンスに選択肢がありません")
+	}
+	if resp.Choices[0].FinishReason == "length" {
+		return "", errTruncated
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// anthropicProvider - Anthropic Messages API（スキーマをツールの入力として指定し、ツールの呼び出しを強制する）
+type anthropicProvider struct {
+	baseURL     string
+	apiKey      string
+	model       string
+	temperature float64
+}
+
+func (p *anthropicProvider) complete(ctx context.Context, system, user string, schema map[string]interface{}) (string, error) {
+	reqBody := map[string]interface{}{
+		"model":       p.model,
+		"system":      system,
+		"max_tokens":  maxTokens,
+		"temperature": p.temperature,
+		"messages": []map[string]string{
+			{"role": "user", "content": user},
+		},
+		"tools": []map[string]interface{}{
+			{"name": "topics", "description": "生成したお題を出力する", "input_schema": schema},
+		},
+		"tool_choice": map[string]string{"type": "tool", "name": "topics"},
+	}
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	body, err := postJSON(ctx, p.baseURL+"/messages", headers, reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("レスポンスのデコードに失敗: %w", err)
+	}
+	if resp.StopReason == "max_tokens" {
+		return "", errTruncated
+	}
+	for _, c := range resp.Content {
+		if c.Type == "tool_use" {
+			return string(c.Input), nil
+		}
+	}
+	return "", fmt.Errorf("レスポンスにお題の出力がありません（stop_reason=%s）", resp.StopReason)
+}
+
+// postJSON - JSONをPOSTしてレスポンスの本文を返す（429・5xx・通信エラーは待ってから再試行する）
+func postJSON(ctx context.Context, url string, headers map[string]string, reqBody interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストのマーシャルに失敗: %w", err)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			wait := time.Duration(1<<attempt) * time.Second
+			log.Printf("警告: APIの呼び出しに失敗（%d回目）、%s後に再試行: %v", attempt-1, wait, lastErr)
+			time.Sleep(wait)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("リクエストの作成に失敗: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("APIの呼び出しに失敗: %w", err)
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("レスポンスの読み込みに失敗: %w", err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+		lastErr = fmt.Errorf("APIエラー: %d - %s", resp.StatusCode, string(body))
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
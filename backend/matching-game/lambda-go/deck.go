@@ -0,0 +1,413 @@
+// deck.go - 名前付きのお題デッキ（JSON・CSVでのインポート・エクスポート、形式はtopicgen/deck.go）
+// デッキはテーマ別のお題の集合（社内クイズ・季節のイベント等）で、ルームはお題の出典として1つ以上のデッキを選択できる
+// デッキのお題は生成したお題と違いカテゴリを自由に付けられるため、ルームのtopicCategoriesの指定はデッキのお題には適用しない
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const (
+	deckTopicSource = "DECK" // デッキから取り出したお題の出典
+
+	maxDeckTopics            = 1000       // 1デッキのお題の上限（DynamoDBの1項目に収まる数）
+	maxDeckContentBytes      = 300 * 1024 // インポートする内容の上限
+	maxDeckNameLength        = 40         // デッキ名の最大文字数
+	maxDeckDescriptionLength = 200        // 説明の最大文字数
+	maxDeckSkippedReported   = 50         // インポート結果に含める取り込まなかった行の上限
+)
+
+// importTopicDeck - JSONまたはCSVからデッキを作成（deckIdを指定した場合は既存のデッキのお題を置き換える）
+// 不正な行は取り込まずにskippedに理由を返し、取り込めるお題が1つもない場合はエラーにする
+func importTopicDeck(ctx context.Context, args map[string]interface{}) (*TopicDeckImportResult, error) {
+	format, _ := args["format"].(string)
+	content, _ := args["content"].(string)
+	name, _ := args["name"].(string)
+	description, _ := args["description"].(string)
+	deckID, _ := args["deckId"].(string)
+
+	log.Printf("デッキのインポート: deckId=%s, format=%s, %dバイト", deckID, format, len(content))
+
+	if len(content) > maxDeckContentBytes {
+		return nil, fmt.Errorf("インポートする内容は%dKB以内にしてください", maxDeckContentBytes/1024)
+	}
+
+	var rows []topicgen.DeckTopic
+	switch format {
+	case "JSON":
+		file, err := topicgen.ParseDeckJSON(content)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = file.Name
+		}
+		if description == "" {
+			description = file.Description
+		}
+		rows = file.Topics
+	case "CSV":
+		parsed, err := topicgen.ParseDeckCSV(content)
+		if err != nil {
+			return nil, err
+		}
+		rows = parsed
+	default:
+		return nil, fmt.Errorf("不明なデッキの形式: %s", format)
+	}
+
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	if name == "" {
+		return nil, fmt.Errorf("デッキ名を指定してください")
+	}
+	if utf8.RuneCountInString(name) > maxDeckNameLength {
+		return nil, fmt.Errorf("デッキ名は%d文字以内で指定してください", maxDeckNameLength)
+	}
+	if utf8.RuneCountInString(description) > maxDeckDescriptionLength {
+		return nil, fmt.Errorf("説明は%d文字以内で指定してください", maxDeckDescriptionLength)
+	}
+
+	topics, skipped := topicgen.ValidateDeckTopics(rows)
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("取り込めるお題がありません（%s）", strings.Join(skipped, "、"))
+	}
+	if len(topics) > maxDeckTopics {
+		return nil, fmt.Errorf("1つのデッキのお題は%d個までです（%d個）", maxDeckTopics, len(topics))
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	deck := &TopicDeck{
+		DeckID:          deckID,
+		Name:            name,
+		Description:     description,
+		CreatedAt:       now,
+		OwnerIdentityID: callerIdentity(ctx),
+	}
+	if deckID != "" {
+		existing, err := getTopicDeck(ctx, deckID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("デッキが見つかりません")
+		}
+		if err := requireDeckOwner(ctx, existing, args); err != nil {
+			return nil, err
+		}
+		deck.CreatedAt = existing.CreatedAt
+		deck.OwnerIdentityID = existing.OwnerIdentityID
+	} else {
+		// 作成した端末が記録されないデッキは管理者しか変更できないため、識別情報のない作成は管理者に限る
+		if deck.OwnerIdentityID == "" && !hasAdminSecret(args) {
+			return nil, fmt.Errorf("デッキの作成には端末の識別情報（Cognito Identity）が必要です")
+		}
+		deck.DeckID = uuid.New().String()
+	}
+	deck.UpdatedAt = now
+	setDeckTopics(deck, topics)
+
+	item, err := attributevalue.MarshalMap(deck)
+	if err != nil {
+		return nil, fmt.Errorf("デッキのマーシャルに失敗: %w", err)
+	}
+	_, err = ddbClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(topicDeckTable),
+		Item:      item,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("デッキの保存に失敗: %w", err)
+	}
+
+	log.Printf("デッキをインポートしました: deckId=%s, name=%s, %d個（取り込まなかった行: %d）", deck.DeckID, deck.Name, len(topics), len(skipped))
+	if len(skipped) > maxDeckSkippedReported {
+		skipped = append(skipped[:maxDeckSkippedReported], fmt.Sprintf("ほか%d行", len(skipped)-maxDeckSkippedReported))
+	}
+	return &TopicDeckImportResult{Deck: deck, Imported: len(topics), Skipped: skipped}, nil
+}
+
+// exportTopicDeck - デッキをJSONまたはCSVの文字列として出力（インポートと同じ形式）
+func exportTopicDeck(ctx context.Context, args map[string]interface{}) (string, error) {
+	deckID := args["deckId"].(string)
+	format, _ := args["format"].(string)
+
+	deck, err := getTopicDeck(ctx, deckID)
+	if err != nil {
+		return "", err
+	}
+	if deck == nil {
+		return "", fmt.Errorf("デッキが見つかりません")
+	}
+
+	switch format {
+	case "JSON":
+		data, err := json.MarshalIndent(topicgen.DeckFile{Name: deck.Name, Description: deck.Description, Topics: deck.Topics}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("デッキのマーシャルに失敗: %w", err)
+		}
+		return string(data), nil
+	case "CSV":
+		return topicgen.FormatDeckCSV(deck.Topics)
+	}
+	return "", fmt.Errorf("不明なデッキの形式: %s", format)
+}
+
+// listTopicDecks - 全デッキの概要（お題は含まない、名前順）
+func listTopicDecks(ctx context.Context, args map[string]interface{}) ([]TopicDeck, error) {
+	decks := []TopicDeck{}
+
+	paginator := dynamodb.NewScanPaginator(ddbClient, &dynamodb.ScanInput{
+		TableName:            aws.String(topicDeckTable),
+		ProjectionExpression: aws.String("deckId, #name, description, topicCount, languages, tags, createdAt, updatedAt"),
+		ExpressionAttributeNames: map[string]string{
+			"#name": "name",
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("デッキのスキャンに失敗: %w", err)
+		}
+		for _, item := range page.Items {
+			var deck TopicDeck
+			if err := attributevalue.UnmarshalMap(item, &deck); err != nil {
+				log.Printf("警告: デッキのアンマーシャルに失敗: %v", err)
+				continue
+			}
+			normalizeDeck(&deck)
+			decks = append(decks, deck)
+		}
+	}
+
+	sort.Slice(decks, func(i, j int) bool { return decks[i].Name < decks[j].Name })
+	return decks, nil
+}
+
+// deleteTopicDeck - デッキを削除（作成した端末または管理者のみ）
+// 削除したデッキを選択しているルームでは、残りのデッキ（ない場合はDECKならエラー、MIXEDならバンク）から出題する
+func deleteTopicDeck(ctx context.Context, args map[string]interface{}) (*TopicDeck, error) {
+	deckID := args["deckId"].(string)
+
+	deck, err := getTopicDeck(ctx, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if deck == nil {
+		return nil, fmt.Errorf("デッキが見つかりません")
+	}
+	if err := requireDeckOwner(ctx, deck, args); err != nil {
+		return nil, err
+	}
+
+	_, err = ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(topicDeckTable),
+		Key: map[string]types.AttributeValue{
+			"deckId": &types.AttributeValueMemberS{Value: deckID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("デッキの削除に失敗: %w", err)
+	}
+
+	log.Printf("デッキを削除しました: deckId=%s, name=%s", deckID, deck.Name)
+	return deck, nil
+}
+
+// getTopicDeck - デッキをお題付きで取得（存在しない場合はnil）
+func getTopicDeck(ctx context.Context, deckID string) (*TopicDeck, error) {
+	result, err := ddbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(topicDeckTable),
+		Key: map[string]types.AttributeValue{
+			"deckId": &types.AttributeValueMemberS{Value: deckID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("デッキの取得に失敗: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var deck TopicDeck
+	if err := attributevalue.UnmarshalMap(result.Item, &deck); err != nil {
+		return nil, fmt.Errorf("デッキのアンマーシャルに失敗: %w", err)
+	}
+	normalizeDeck(&deck)
+	return &deck, nil
+}
+
+// requireDeckOwner - デッキを変更できるか確認（作成した端末、または管理者シークレットを指定した場合）
+// 作成した端末が記録されていないデッキ（管理者が作成したデッキ）は管理者シークレットが必要
+func requireDeckOwner(ctx context.Context, deck *TopicDeck, args map[string]interface{}) error {
+	if deck.OwnerIdentityID != "" && deck.OwnerIdentityID == callerIdentity(ctx) {
+		return nil
+	}
+	if hasAdminSecret(args) {
+		return nil
+	}
+	return fmt.Errorf("デッキを作成した端末のみが変更できます")
+}
+
+// hasAdminSecret - 正しい管理者シークレットが指定されているか
+func hasAdminSecret(args map[string]interface{}) bool {
+	_, ok := args["adminSecret"].(string)
+	return ok && requireAdmin(args) == nil
+}
+
+// normalizeDeck - DBから読み込んだデッキの欠損値を補完
+func normalizeDeck(deck *TopicDeck) {
+	if deck.Languages == nil {
+		deck.Languages = []string{}
+	}
+	if deck.Tags == nil {
+		deck.Tags = []string{}
+	}
+	if deck.Topics == nil {
+		deck.Topics = []topicgen.DeckTopic{}
+	}
+	for i := range deck.Topics {
+		if deck.Topics[i].Tags == nil {
+			deck.Topics[i].Tags = []string{}
+		}
+	}
+}
+
+// setDeckTopics - デッキのお題と、お題から集計する数・言語・タグを設定
+func setDeckTopics(deck *TopicDeck, topics []topicgen.DeckTopic) {
+	deck.Topics = topics
+	deck.TopicCount = len(topics)
+	deck.Languages = []string{}
+	deck.Tags = []string{}
+	for _, t := range topics {
+		if !slices.Contains(deck.Languages, t.Language) {
+			deck.Languages = append(deck.Languages, t.Language)
+		}
+		for _, tag := range t.Tags {
+			if !slices.Contains(deck.Tags, tag) {
+				deck.Tags = append(deck.Tags, tag)
+			}
+		}
+	}
+	sort.Strings(deck.Languages)
+	sort.Strings(deck.Tags)
+}
+
+// loadDeckTopics - ルームが選択したデッキのお題（出題用のTopicに変換、存在しないデッキは飛ばす）
+func loadDeckTopics(ctx context.Context, deckIDs []string) ([]Topic, error) {
+	if len(deckIDs) == 0 {
+		return []Topic{}, nil
+	}
+
+	// 処理されなかったキーはbatchGetItemsが待ってから再取得する
+	items, err := batchGetItems(ctx, topicDeckTable, deckKeys(deckIDs), types.KeysAndAttributes{})
+	if err != nil {
+		return nil, fmt.Errorf("デッキの取得に失敗: %w", err)
+	}
+
+	decks := make([]TopicDeck, 0, len(items))
+	for _, item := range items {
+		var deck TopicDeck
+		if err := attributevalue.UnmarshalMap(item, &deck); err != nil {
+			log.Printf("警告: デッキのアンマーシャルに失敗: %v", err)
+			continue
+		}
+		decks = append(decks, deck)
+	}
+	if len(items) < len(deckIDs) {
+		log.Printf("警告: 選択されたデッキの一部が見つかりません: %v", deckIDs)
+	}
+	return deckTopics(decks), nil
+}
+
+// deckTopics - デッキのお題を出題用のTopicに変換（複数のデッキにある同じお題は1つにまとめる）
+func deckTopics(decks []TopicDeck) []Topic {
+	topics := []Topic{}
+	added := make(map[string]bool)
+	for _, deck := range decks {
+		for _, t := range deck.Topics {
+			if added[t.Topic] {
+				continue
+			}
+			added[t.Topic] = true
+			answers := t.ExampleAnswers
+			if answers == nil {
+				answers = []string{}
+			}
+			topics = append(topics, Topic{
+				Text:           t.Topic,
+				Category:       t.Category,
+				ExampleAnswers: answers,
+				Source:         deckTopicSource,
+				Difficulty:     t.Difficulty,
+			})
+		}
+	}
+	return topics
+}
+
+// requireTopicDecksExist - ルーム設定で選択したデッキがすべて存在するか確認
+func requireTopicDecksExist(ctx context.Context, deckIDs []string) error {
+	if len(deckIDs) == 0 {
+		return nil
+	}
+
+	items, err := batchGetItems(ctx, topicDeckTable, deckKeys(deckIDs), types.KeysAndAttributes{
+		ProjectionExpression: aws.String("deckId"),
+	})
+	if err != nil {
+		return fmt.Errorf("デッキの取得に失敗: %w", err)
+	}
+	found := make(map[string]bool)
+	for _, item := range items {
+		if v, ok := item["deckId"].(*types.AttributeValueMemberS); ok {
+			found[v.Value] = true
+		}
+	}
+	for _, id := range deckIDs {
+		if !found[id] {
+			return fmt.Errorf("デッキが見つかりません: %s", id)
+		}
+	}
+	return nil
+}
+
+// deckKeys - デッキIDのリストをBatchGetItemのキーに変換
+func deckKeys(deckIDs []string) []map[string]types.AttributeValue {
+	keys := make([]map[string]types.AttributeValue, 0, len(deckIDs))
+	for _, id := range deckIDs {
+		keys = append(keys, map[string]types.AttributeValue{
+			"deckId": &types.AttributeValueMemberS{Value: id},
+		})
+	}
+	return keys
+}
+
+// interleaveTopics - 2つのお題のリストを交互に並べる（片方が尽きたら残りを続ける）
+func interleaveTopics(a, b []Topic) []Topic {
+	merged := make([]Topic, 0, len(a)+len(b))
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i < len(a) {
+			merged = append(merged, a[i])
+		}
+		if i < len(b) {
+			merged = append(merged, b[i])
+		}
+	}
+	return merged
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+func TestRequireDeckOwner(t *testing.T) {
+	t.Setenv("ADMIN_SECRET", "secret")
+	owner := context.WithValue(context.Background(), callerIdentityKey{}, "owner-id")
+	other := context.WithValue(context.Background(), callerIdentityKey{}, "other-id")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		deck    TopicDeck
+		args    map[string]interface{}
+		wantErr bool
+	}{
+		{"作成した端末", owner, TopicDeck{OwnerIdentityID: "owner-id"}, nil, false},
+		{"別の端末", other, TopicDeck{OwnerIdentityID: "owner-id"}, nil, true},
+		{"識別情報のない呼び出し", context.Background(), TopicDeck{OwnerIdentityID: "owner-id"}, nil, true},
+		{"作成者のないデッキは識別情報がなくても変更できない", context.Background(), TopicDeck{}, nil, true},
+		{"管理者", other, TopicDeck{OwnerIdentityID: "owner-id"}, map[string]interface{}{"adminSecret": "secret"}, false},
+		{"管理者は作成者のないデッキも変更できる", context.Background(), TopicDeck{}, map[string]interface{}{"adminSecret": "secret"}, false},
+		{"誤った管理者シークレット", other, TopicDeck{OwnerIdentityID: "owner-id"}, map[string]interface{}{"adminSecret": "wrong"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := requireDeckOwner(tt.ctx, &tt.deck, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportTopicDeckValidation(t *testing.T) {
+	t.Setenv("ADMIN_SECRET", "secret")
+	ctx := context.WithValue(context.Background(), callerIdentityKey{}, "owner-id")
+	csv := "topic\n朝ごはんといえば？\n"
+
+	// いずれもデッキを保存する前に失敗する
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"不明な形式", ctx, map[string]interface{}{"format": "XML", "content": csv, "name": "朝"}, "不明なデッキの形式"},
+		{"内容が大きすぎる", ctx, map[string]interface{}{"format": "CSV", "content": strings.Repeat("a", maxDeckContentBytes+1), "name": "朝"}, "KB以内"},
+		{"デッキ名なし", ctx, map[string]interface{}{"format": "CSV", "content": csv, "name": " "}, "デッキ名を指定"},
+		{"デッキ名が長すぎる", ctx, map[string]interface{}{"format": "CSV", "content": csv, "name": strings.Repeat("あ", maxDeckNameLength+1)}, "デッキ名は"},
+		{"取り込めるお題なし", ctx, map[string]interface{}{"format": "CSV", "content": "topic,difficulty\n朝ごはん,EXTREME\n", "name": "朝"}, "取り込めるお題がありません"},
+		{"JSONの解析に失敗", ctx, map[string]interface{}{"format": "JSON", "content": "{", "name": "朝"}, ""},
+		{"識別情報のない作成", context.Background(), map[string]interface{}{"format": "CSV", "content": csv, "name": "朝"}, "識別情報"},
+		{"識別情報のない作成に誤った管理者シークレット", context.Background(), map[string]interface{}{"format": "CSV", "content": csv, "name": "朝", "adminSecret": "wrong"}, "識別情報"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := importTopicDeck(tt.ctx, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("result = %+v, err = %v, want %q を含むエラー", result, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeckTopics(t *testing.T) {
+	decks := []TopicDeck{
+		{DeckID: "d1", Topics: []topicgen.DeckTopic{
+			{Topic: "朝ごはんといえば？", Category: "食べ物", Difficulty: "EASY", ExampleAnswers: []string{"パン"}},
+			{Topic: "夏といえば？"},
+		}},
+		{DeckID: "d2", Topics: []topicgen.DeckTopic{
+			{Topic: "夏といえば？", Category: "季節", ExampleAnswers: []string{"海"}},
+			{Topic: "冬といえば？", Difficulty: "HARD", ExampleAnswers: []string{"雪"}},
+		}},
+	}
+
+	want := []Topic{
+		{Text: "朝ごはんといえば？", Category: "食べ物", ExampleAnswers: []string{"パン"}, Source: deckTopicSource, Difficulty: "EASY"},
+		{Text: "夏といえば？", ExampleAnswers: []string{}, Source: deckTopicSource},
+		{Text: "冬といえば？", ExampleAnswers: []string{"雪"}, Source: deckTopicSource, Difficulty: "HARD"},
+	}
+	if got := deckTopics(decks); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if got := deckTopics(nil); got == nil || len(got) != 0 {
+		t.Errorf("デッキなし: %#v", got)
+	}
+}
+
+func TestLoadDeckTopicsWithoutDecks(t *testing.T) {
+	// デッキを選択していないルームはDBを読まずに空のリストを返す
+	topics, err := loadDeckTopics(context.Background(), nil)
+	if err != nil || topics == nil || len(topics) != 0 {
+		t.Fatalf("topics = %#v, err = %v", topics, err)
+	}
+	if err := requireTopicDecksExist(context.Background(), []string{}); err != nil {
+		t.Fatal(err)
+	}
+}
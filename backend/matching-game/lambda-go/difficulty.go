@@ -0,0 +1,208 @@
+// difficulty.go - 判定結果の記録からお題の難易度を推定し、ルーム設定の難易度に合わせて出題する
+// 難易度は不一致の割合と回答の割れ方（回答者数に対するグループ数）から推定し、記録の少ないお題は想定回答の数で補う
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const (
+	defaultTopicDifficulty = "ANY" // お題の難易度（既定は指定なし）
+
+	easyDifficultyMax = 0.4 // 推定難易度がこの値未満のお題はEASY
+	hardDifficultyMin = 0.6 // 推定難易度がこの値以上のお題はHARD
+
+	difficultyPriorWeight     = 3.0 // 想定回答の数から見積もった難易度を何ラウンド分の記録として扱うか
+	progressionRoundsPerLevel = 5   // ラウンド数無制限のPROGRESSIVEで難易度を1段階上げるラウンド数
+)
+
+// validTopicDifficulties - 選択可能なお題の難易度（区分のEASY・MEDIUM・HARDはtopicgen.Difficultiesと共通）
+var validTopicDifficulties = func() map[string]bool {
+	valid := map[string]bool{
+		"ANY":         true, // 難易度を指定しない
+		"PROGRESSIVE": true, // EASYから始めてラウンドが進むごとに難しくする
+	}
+	for _, d := range topicgen.Difficulties {
+		valid[d.Level] = true
+	}
+	return valid
+}()
+
+// roundOutcome - 判定したラウンドの結果（お題の難易度の記録単位）
+type roundOutcome struct {
+	IsMatch bool // 一致と判定されたか
+	Groups  int  // 回答のグループ数（表記の正規化でまとめた数）
+	Players int  // 回答者数
+}
+
+// outcomeFromAnswers - 回答と判定結果からラウンドの結果を作成
+func outcomeFromAnswers(answers []Answer, isMatch bool) roundOutcome {
+	return roundOutcome{
+		IsMatch: isMatch,
+		Groups:  len(groupAnswers(answers, nil)),
+		Players: len(answers),
+	}
+}
+
+// outcomeFromGroups - 多数派モードの判定結果からラウンドの結果を作成（全員が同じグループの場合を一致とする）
+func outcomeFromGroups(groups []AnswerGroup) roundOutcome {
+	players := 0
+	for _, g := range groups {
+		players += len(g.Members)
+	}
+	return roundOutcome{
+		IsMatch: len(groups) == 1 && players > 1,
+		Groups:  len(groups),
+		Players: players,
+	}
+}
+
+// recordTopicOutcome - お題の判定結果を記録（失敗しても進行は止めない）
+// 同じラウンドで判定をやり直した場合は、previousに前回の結果を渡して差し替える
+func recordTopicOutcome(ctx context.Context, topic string, outcome roundOutcome, previous *roundOutcome) {
+	deltas := map[string]int{
+		"matchCount":     0,
+		"missCount":      0,
+		"judgedRounds":   1,
+		"groupCountSum":  outcome.Groups,
+		"playerCountSum": outcome.Players,
+	}
+	if previous != nil {
+		if previous.IsMatch {
+			deltas["matchCount"]--
+		} else {
+			deltas["missCount"]--
+		}
+		deltas["judgedRounds"]--
+		deltas["groupCountSum"] -= previous.Groups
+		deltas["playerCountSum"] -= previous.Players
+	}
+	if outcome.IsMatch {
+		deltas["matchCount"]++
+	} else {
+		deltas["missCount"]++
+	}
+	addTopicStats(ctx, topic, deltas)
+}
+
+// answersPrior - 想定回答の数（0は不明）から見積もった難易度
+func answersPrior(exampleAnswers int) float64 {
+	switch {
+	case exampleAnswers == 1:
+		return 0.25
+	case exampleAnswers >= topicgen.MaxExampleAnswers:
+		return 0.75
+	}
+	return 0.5
+}
+
+// difficulty - 記録から推定した難易度（0〜1）
+// priorは記録が少ない間の見積もり（想定回答の数・デッキで指定された難易度から求める）
+func (s topicStatsItem) difficulty(prior float64) float64 {
+	rounds := float64(s.MatchCount + s.MissCount)
+	if rounds <= 0 {
+		return prior
+	}
+
+	// 不一致の割合と、回答の割れ方（全員同じ答えで0、全員違う答えで1）の平均
+	raw := float64(s.MissCount) / rounds
+	if s.JudgedRounds > 0 && s.PlayerCountSum > s.JudgedRounds {
+		spread := float64(s.GroupCountSum-s.JudgedRounds) / float64(s.PlayerCountSum-s.JudgedRounds)
+		raw = (raw + math.Max(0, math.Min(1, spread))) / 2
+	}
+
+	return (raw*rounds + prior*difficultyPriorWeight) / (rounds + difficultyPriorWeight)
+}
+
+// difficultyLevel - 推定難易度の区分
+func difficultyLevel(d float64) string {
+	switch {
+	case d < easyDifficultyMax:
+		return "EASY"
+	case d >= hardDifficultyMin:
+		return "HARD"
+	}
+	return "MEDIUM"
+}
+
+// topicDifficulty - お題の推定難易度（記録がない場合はデッキで指定された難易度か想定回答の数から見積もる）
+func topicDifficulty(t Topic, stats map[string]topicStatsItem) float64 {
+	if center, ok := topicgen.DifficultyCenter(t.Difficulty); ok {
+		return stats[t.Text].difficulty(center)
+	}
+	return stats[t.Text].difficulty(answersPrior(len(t.ExampleAnswers)))
+}
+
+// targetDifficulty - ラウンドで出題する難易度の区分（指定なしの場合は空文字）
+// PROGRESSIVEはラウンド数を3等分してEASY→MEDIUM→HARDと上げる（ラウンド数無制限の場合は一定ラウンドごと）
+func targetDifficulty(settings *RoomSettings, round int) string {
+	switch settings.TopicDifficulty {
+	case "EASY", "MEDIUM", "HARD":
+		return settings.TopicDifficulty
+	case "PROGRESSIVE":
+		stage := (round - 1) / progressionRoundsPerLevel
+		if settings.MaxRounds > 0 {
+			stage = (round - 1) * 3 / settings.MaxRounds
+		}
+		switch {
+		case stage <= 0:
+			return "EASY"
+		case stage == 1:
+			return "MEDIUM"
+		}
+		return "HARD"
+	}
+	return ""
+}
+
+// pickNextTopic - お題プールからラウンドで出題するお題を選び、残りのプールと合わせて返す
+// 難易度の指定がない場合は先頭のお題、ある場合は区分が合う最初のお題（ない場合は最も近いお題）を選ぶ
+// 先頭がホストが位置を指定したお題の場合は、難易度の指定によらずそのお題を出題する
+func pickNextTopic(ctx context.Context, settings *RoomSettings, round int, pool []Topic) (Topic, []Topic) {
+	target := targetDifficulty(settings, round)
+	index := 0
+	if target != "" && !pool[0].Pinned {
+		stats := loadTopicStats(ctx, topicTexts(pool))
+		best := math.Inf(1)
+		for i, t := range pool {
+			d := topicDifficulty(t, stats)
+			if difficultyLevel(d) == target {
+				index = i
+				break
+			}
+			center, _ := topicgen.DifficultyCenter(target)
+			if dist := math.Abs(d - center); dist < best {
+				best = dist
+				index = i
+			}
+		}
+	}
+
+	rest := append(append([]Topic{}, pool[:index]...), pool[index+1:]...)
+	return pool[index], rest
+}
+
+// preferDifficulty - 難易度を固定したルーム向けに、区分が合うお題を先にしてランダムに並べる
+// PROGRESSIVEや指定なしの場合は全区分を混ぜたままランダムに並べる
+func preferDifficulty(ctx context.Context, settings *RoomSettings, topics []Topic) []Topic {
+	rand.Shuffle(len(topics), func(i, j int) { topics[i], topics[j] = topics[j], topics[i] })
+	if settings.TopicDifficulty == "ANY" || settings.TopicDifficulty == "PROGRESSIVE" {
+		return topics
+	}
+
+	stats := loadTopicStats(ctx, topicTexts(topics))
+	matched := []Topic{}
+	others := []Topic{}
+	for _, t := range topics {
+		if difficultyLevel(topicDifficulty(t, stats)) == settings.TopicDifficulty {
+			matched = append(matched, t)
+		} else {
+			others = append(others, t)
+		}
+	}
+	return append(matched, others...)
+}
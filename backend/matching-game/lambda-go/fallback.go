@@ -0,0 +1,107 @@
+// fallback.go - OpenAI APIが使えない場合（障害・ブレーカー作動中）に使う組み込みのお題
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+)
+
+// fallbackTopics - 組み込みのお題（各カテゴリから数問ずつ）
+var fallbackTopics = []Topic{
+	{Text: "コンビニのおにぎりで一番人気の具といえば？", Category: "食べ物・飲み物", ExampleAnswers: []string{"ツナマヨ", "鮭"}},
+	{Text: "給食の人気メニューといえば？", Category: "食べ物・飲み物", ExampleAnswers: []string{"カレー", "揚げパン"}},
+	{Text: "お祭りの屋台の定番といえば？", Category: "食べ物・飲み物", ExampleAnswers: []string{"たこ焼き", "焼きそば"}},
+	{Text: "赤いフルーツといえば？", Category: "食べ物・飲み物", ExampleAnswers: []string{"いちご", "りんご"}},
+	{Text: "修学旅行で行く定番の場所といえば？", Category: "場所・観光地", ExampleAnswers: []string{"京都", "奈良"}},
+	{Text: "日本一高い山といえば？", Category: "場所・観光地", ExampleAnswers: []string{"富士山"}},
+	{Text: "ドラえもんの道具の定番といえば？", Category: "キャラクター・アニメ", ExampleAnswers: []string{"どこでもドア", "タケコプター"}},
+	{Text: "アンパンマンのライバルといえば？", Category: "キャラクター・アニメ", ExampleAnswers: []string{"ばいきんまん"}},
+	{Text: "運動会の定番競技といえば？", Category: "学校・行事", ExampleAnswers: []string{"リレー", "玉入れ"}},
+	{Text: "夏休みの宿題の定番といえば？", Category: "学校・行事", ExampleAnswers: []string{"読書感想文", "自由研究"}},
+	{Text: "動物園の人気者といえば？", Category: "動物・生き物", ExampleAnswers: []string{"パンダ", "ゾウ"}},
+	{Text: "水族館のショーの主役といえば？", Category: "動物・生き物", ExampleAnswers: []string{"イルカ"}},
+	{Text: "黄色い乗り物といえば？", Category: "色・形・特徴", ExampleAnswers: []string{"タクシー", "ドクターイエロー"}},
+	{Text: "丸い食べ物といえば？", Category: "色・形・特徴", ExampleAnswers: []string{"たこ焼き", "おにぎり"}},
+	{Text: "ファストフードの定番チェーンといえば？", Category: "お店・チェーン", ExampleAnswers: []string{"マクドナルド"}},
+	{Text: "牛丼チェーンといえば？", Category: "お店・チェーン", ExampleAnswers: []string{"吉野家", "すき家"}},
+	{Text: "日本で一番速い電車といえば？", Category: "乗り物・交通", ExampleAnswers: []string{"新幹線"}},
+	{Text: "東京の環状線といえば？", Category: "乗り物・交通", ExampleAnswers: []string{"山手線"}},
+	{Text: "日本で一番人気のスポーツといえば？", Category: "スポーツ・遊び", ExampleAnswers: []string{"野球", "サッカー"}},
+	{Text: "じゃんけんで最初に出す手といえば？", Category: "スポーツ・遊び", ExampleAnswers: []string{"グー"}},
+	{Text: "年末の歌番組といえば？", Category: "その他", ExampleAnswers: []string{"紅白歌合戦"}},
+	{Text: "お正月にもらうものといえば？", Category: "その他", ExampleAnswers: []string{"お年玉"}},
+}
+
+// fallbackWordPairs - ワードウルフ用の組み込みのお題の組
+var fallbackWordPairs = [][2]string{
+	{"うどん", "そば"},
+	{"海", "プール"},
+	{"コンビニ", "スーパー"},
+	{"犬", "猫"},
+	{"コーヒー", "紅茶"},
+	{"電車", "バス"},
+	{"野球", "ソフトボール"},
+	{"ラーメン", "つけ麺"},
+	{"映画館", "美術館"},
+	{"夏祭り", "花火大会"},
+	{"ケーキ", "パフェ"},
+	{"図書館", "本屋"},
+}
+
+// pickFallbackTopics - 使用済み・指定外のカテゴリを除いた組み込みのお題
+func pickFallbackTopics(usedTopics []string, categories []string) []Topic {
+	topics := []Topic{}
+	for _, t := range fallbackTopics {
+		if slices.Contains(usedTopics, t.Text) {
+			continue
+		}
+		if len(categories) > 0 && !slices.Contains(categories, t.Category) {
+			continue
+		}
+		topics = append(topics, t)
+	}
+	return topics
+}
+
+// pickFallbackWordPair - 使用済みでない組み込みのお題の組をランダムに選ぶ（全て使用済みの場合はfalse）
+// 組み込みの組はカテゴリを持たないため、カテゴリ指定は考慮しない
+func pickFallbackWordPair(usedPairs []string) (string, string, bool) {
+	for _, i := range rand.Perm(len(fallbackWordPairs)) {
+		p := fallbackWordPairs[i]
+		if slices.Contains(usedPairs, p[0]+"/"+p[1]) || slices.Contains(usedPairs, p[1]+"/"+p[0]) {
+			continue
+		}
+		return p[0], p[1], true
+	}
+	return "", "", false
+}
+
+// fallbackTopicsFor - お題の生成に失敗した場合に使う組み込みのお題（ランダムな順）
+func fallbackTopicsFor(usedTopics []string, categories []string, cause error) ([]Topic, error) {
+	log.Printf("警告: お題の生成に失敗したため組み込みのお題を使用: %v", cause)
+
+	topics := pickFallbackTopics(usedTopics, categories)
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("組み込みのお題も使い切りました: %w", cause)
+	}
+	rand.Shuffle(len(topics), func(i, j int) { topics[i], topics[j] = topics[j], topics[i] })
+	return topics, nil
+}
+
+// generateWordPair - ワードウルフのお題の組を生成（OpenAI APIが使えない場合は組み込みの組を返す）
+func generateWordPair(ctx context.Context, usedPairs []string, categories []string) (string, string, error) {
+	majority, minority, err := requestWordPair(ctx, usedPairs, categories)
+	if err == nil {
+		return majority, minority, nil
+	}
+	log.Printf("警告: お題の組の生成に失敗したため組み込みの組を使用: %v", err)
+
+	majority, minority, ok := pickFallbackWordPair(usedPairs)
+	if !ok {
+		return "", "", fmt.Errorf("組み込みのお題の組も使い切りました: %w", err)
+	}
+	return majority, minority, nil
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,8 +16,44 @@ import (
 	"github.com/google/uuid"
 )
 
-// startGame - ゲームを開始
-// お題プールを生成し、最初のお題を設定する
+// matchingGame - 認識合わせ（全員の回答が一致するかを判定する、最初のゲーム）
+type matchingGame struct{}
+
+// Start - お題を出してANSWERINGにする
+func (matchingGame) Start(ctx context.Context, svc *GameServices, room *Room) (*Room, error) {
+	return startMatching(ctx, svc, room)
+}
+
+// IsRoundInProgress - お題が出ている（回答・判定中）か
+func (matchingGame) IsRoundInProgress(state string) bool {
+	return state == "ANSWERING" || state == "JUDGING"
+}
+
+// Resolvers - 回答・判定・ラウンド進行・お題キュー・チーム分けのフィールド
+// judgeAnswers（一致の判定）とjudgeMajority（多数派モード）は戻り値の型が異なるため、得点ルールに合うフィールドのみ受け付ける
+func (matchingGame) Resolvers() map[string]resolverFunc {
+	return map[string]resolverFunc{
+		"submitAnswer":            answerResolver("MATCHING", false),
+		"startJudging":            resolver(startJudging),
+		"generateJudgingComments": resolver(generateJudgingComments),
+		"judgeAnswers":            judgeResolver("MATCHING", requireScoringRule(false)),
+		"judgeMajority":           judgeResolver("MATCHING", requireScoringRule(true)),
+		"nextRound":               resolver(nextRound),
+		"skipTopic":               resolver(skipTopic),
+		"rateTopic":               resolver(rateTopic),
+		"getTopicQueue":           resolver(getTopicQueue),
+		"addCustomTopic":          resolver(addCustomTopic),
+		"removeQueuedTopic":       resolver(removeQueuedTopic),
+		"moveQueuedTopic":         resolver(moveQueuedTopic),
+		"submitTopic":             resolver(submitTopic),
+		"rejectTopicSubmission":   resolver(rejectTopicSubmission),
+		"assignTeam":              resolver(assignTeam),
+		"balanceTeams":            resolver(balanceTeams),
+	}
+}
+
+// startGame - ゲームを開始（全ゲーム共通）
+// 最小人数・準備完了を確認し、ルームのゲームの種類に応じた開始処理を呼ぶ
 func startGame(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	roomID := args["roomId"].(string)
 	log.Printf("ゲーム開始: roomId=%s", roomID)
@@ -29,49 +67,107 @@ func startGame(ctx context.Context, args map[string]interface{}) (*Room, error)
 		return nil, fmt.Errorf("ルームが見つかりません")
 	}
 
-	// お題を5個生成
-	log.Println("お題を5個生成中...")
-	newTopics, err := generateTopics(room.UsedTopics)
+	// 最小人数・準備完了を確認
+	if err := checkReadyToStart(room); err != nil {
+		return nil, err
+	}
+
+	game, err := lookupGame(room.GameType)
+	if err != nil {
+		return nil, err
+	}
+
+	extendRoomTTL(ctx, room)
+
+	return game.Start(ctx, gameServices, room)
+}
+
+// startMatching - 認識合わせを開始
+// お題プールを生成し、最初のお題を設定する
+func startMatching(ctx context.Context, svc *GameServices, room *Room) (*Room, error) {
+	roomID := room.RoomID
+
+	// チーム戦では未所属のプレイヤーをチームに割り当てる
+	if err := assignUnassignedTeams(ctx, room.Players, room.Settings.TeamCount); err != nil {
+		return nil, err
+	}
+
+	// 共有のお題バンクからお題を取り出し、ホストが位置を指定したお題はその前に残す
+	pinned := pinnedTopics(room.TopicsPool)
+	newTopics, err := drawTopics(ctx, room, append(append([]string{}, room.UsedTopics...), topicTexts(pinned)...))
 	if err != nil {
-		return nil, fmt.Errorf("お題の生成に失敗: %w", err)
+		return nil, fmt.Errorf("お題の取得に失敗: %w", err)
 	}
-	log.Printf("生成されたお題: %v", newTopics)
+	newTopics = append(pinned, newTopics...)
 
 	// 最初のお題を取り出し、残りをプールに保存
-	firstTopic := newTopics[0]
-	remainingTopics := newTopics[1:]
+	firstTopic, restTopics := pickNextTopic(ctx, room.Settings, 1, newTopics)
+	remainingTopics, err := marshalTopicList(restTopics)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now().UTC().Format(time.RFC3339)
 
-	usedTopics := append(room.UsedTopics, firstTopic)
+	usedTopics := append(room.UsedTopics, firstTopic.Text)
+
+	// ルームを更新（状態をANSWERINGに変更、ラウンドと得点をリセット）
+	names := map[string]string{
+		"#state":      "state",
+		"#topicsPool": "topicsPool",
+		"#usedTopics": "usedTopics",
+		"#round":      "round",
+		"#score":      "score",
+		"#updatedAt":  "updatedAt",
+	}
+	values := map[string]types.AttributeValue{
+		":state":      &types.AttributeValueMemberS{Value: "ANSWERING"},
+		":topicsPool": remainingTopics,
+		":usedTopics": marshalStringList(usedTopics),
+		":round":      &types.AttributeValueMemberN{Value: "1"},
+		":score":      &types.AttributeValueMemberN{Value: "0"},
+		":updatedAt":  &types.AttributeValueMemberS{Value: now},
+	}
+	setTopic, removeTopic := topicUpdate(firstTopic, names, values)
+	setDeadline, removeDeadline := answerDeadlineUpdate(answerDeadline(room.Settings), names, values)
+	expr := "SET #state = :state, " + setTopic + ", #topicsPool = :topicsPool, #usedTopics = :usedTopics, #round = :round, #score = :score, #updatedAt = :updatedAt" + setDeadline
+
+	// チームごとの得点もリセット
+	names["#teamScores"] = "teamScores"
+	names["#lastTeamVerdicts"] = "lastTeamVerdicts"
+	names["#lastRoundResult"] = "lastRoundResult"
+	remove := "#lastTeamVerdicts, #lastRoundResult"
+	if room.Settings.TeamCount > 0 {
+		teamScores, err := attributevalue.Marshal(newTeamScores(room.Settings.TeamCount))
+		if err != nil {
+			return nil, fmt.Errorf("得点のマーシャルに失敗: %w", err)
+		}
+		values[":teamScores"] = teamScores
+		expr += ", #teamScores = :teamScores"
+	} else {
+		remove += ", #teamScores"
+	}
+	if removeTopic != "" {
+		remove += ", " + removeTopic
+	}
+	if removeDeadline != "" {
+		remove += ", " + removeDeadline
+	}
+	expr += " REMOVE " + remove + queueVersionUpdate(names, values)
 
-	// ルームを更新（状態をANSWERINGに変更）
-	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(roomTable),
-		Key: map[string]types.AttributeValue{
-			"roomId": &types.AttributeValueMemberS{Value: roomID},
-		},
-		UpdateExpression: aws.String("SET #state = :state, #topic = :topic, #topicsPool = :topicsPool, #usedTopics = :usedTopics, #updatedAt = :updatedAt"),
-		ExpressionAttributeNames: map[string]string{
-			"#state":      "state",
-			"#topic":      "topic",
-			"#topicsPool": "topicsPool",
-			"#usedTopics": "usedTopics",
-			"#updatedAt":  "updatedAt",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":state":      &types.AttributeValueMemberS{Value: "ANSWERING"},
-			":topic":      &types.AttributeValueMemberS{Value: firstTopic},
-			":topicsPool": marshalStringList(remainingTopics),
-			":usedTopics": marshalStringList(usedTopics),
-			":updatedAt":  &types.AttributeValueMemberS{Value: now},
-		},
-	})
+	err = svc.UpdateRoom(ctx, roomID, RoomUpdate{Expression: expr, Names: names, Values: values})
 	if err != nil {
 		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
 	}
 
+	recordSeenTopic(ctx, room.Players, firstTopic.Text)
+	maybePrefetchTopics(ctx, roomID, len(restTopics))
+
+	// 次のゲームに備えて準備完了・個人得点をリセット
+	resetReady(ctx, room.Players)
+	resetPlayerScores(ctx, room.Players)
+
 	// 更新後のルーム情報を取得して返す
-	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	updatedRoom, err := svc.GetRoom(ctx, roomID)
 	if err != nil {
 		return nil, err
 	}
@@ -79,10 +175,8 @@ func startGame(ctx context.Context, args map[string]interface{}) (*Room, error)
 	return updatedRoom, nil
 }
 
-// submitAnswer - 回答を提出
-func submitAnswer(ctx context.Context, args map[string]interface{}) (*Answer, error) {
-	roomID := args["roomId"].(string)
-	playerID := args["playerId"].(string)
+// SubmitAnswer - 回答を提出（submitAnswer）
+func (matchingGame) SubmitAnswer(ctx context.Context, svc *GameServices, room *Room, player *Player, args map[string]interface{}) (interface{}, error) {
 	answerType := args["answerType"].(string)
 
 	// オプショナルな引数を取得
@@ -94,44 +188,43 @@ func submitAnswer(ctx context.Context, args map[string]interface{}) (*Answer, er
 		drawingData = &dd
 	}
 
-	answerID := uuid.New().String()
-	now := time.Now().UTC().Format(time.RFC3339)
-
-	// プレイヤー名を取得
-	playerResult, err := ddbClient.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(playerTable),
-		Key: map[string]types.AttributeValue{
-			"playerId": &types.AttributeValueMemberS{Value: playerID},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("プレイヤーの取得に失敗: %w", err)
+	// 制限時間を過ぎた回答は受け付けない
+	if isPastDeadline(room.AnswerDeadline) {
+		return nil, fmt.Errorf("回答の制限時間を過ぎています")
 	}
-
-	playerName := "Unknown"
-	if playerResult.Item != nil {
-		var player Player
-		if err := attributevalue.UnmarshalMap(playerResult.Item, &player); err == nil {
-			playerName = player.Name
-		}
+	// 観戦者は回答できない（判定・得点にも含めない）
+	if player.Role == "SPECTATOR" {
+		return nil, fmt.Errorf("観戦者は回答できません")
+	}
+	// ラウンド途中に参加したプレイヤーは次のラウンドから回答できる
+	if player.WaitingForNextRound {
+		return nil, fmt.Errorf("次のラウンドから回答できます")
 	}
 
-	// 回答データを作成
+	// 回答データを作成（TTLはルームに合わせる）
 	answer := Answer{
-		AnswerID:    answerID,
-		RoomID:      roomID,
-		PlayerID:    playerID,
-		PlayerName:  playerName,
+		AnswerID:    uuid.New().String(),
+		RoomID:      room.RoomID,
+		PlayerID:    player.PlayerID,
+		PlayerName:  player.Name,
 		AnswerType:  answerType,
 		TextAnswer:  textAnswer,
 		DrawingData: drawingData,
-		SubmittedAt: now,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339),
+		TTL:         room.TTL,
+	}
+	if err := svc.SaveAnswer(ctx, answer); err != nil {
+		return nil, err
 	}
 
-	// DynamoDBに保存
+	return &answer, nil
+}
+
+// saveAnswer - 回答をDynamoDBに保存
+func saveAnswer(ctx context.Context, answer Answer) error {
 	answerItem, err := attributevalue.MarshalMap(answer)
 	if err != nil {
-		return nil, fmt.Errorf("回答のマーシャルに失敗: %w", err)
+		return fmt.Errorf("回答のマーシャルに失敗: %w", err)
 	}
 
 	_, err = ddbClient.PutItem(ctx, &dynamodb.PutItemInput{
@@ -139,20 +232,34 @@ func submitAnswer(ctx context.Context, args map[string]interface{}) (*Answer, er
 		Item:      answerItem,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("回答の作成に失敗: %w", err)
+		return fmt.Errorf("回答の作成に失敗: %w", err)
 	}
-
-	return &answer, nil
+	return nil
 }
 
 // startJudging - 判定画面に遷移
 // 状態をJUDGINGに変更し、コメントを生成する
 func startJudging(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
 	now := time.Now().UTC().Format(time.RFC3339)
 
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return nil, err
+	}
+	if err := requireGameControl(ctx, room, playerID); err != nil {
+		return nil, err
+	}
+
 	// 状態をJUDGINGに更新（コメントはまだ空）
-	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(roomTable),
 		Key: map[string]types.AttributeValue{
 			"roomId": &types.AttributeValueMemberS{Value: roomID},
@@ -199,6 +306,15 @@ func generateJudgingComments(ctx context.Context, args map[string]interface{}) (
 	if room == nil {
 		return nil, fmt.Errorf("ルームが見つかりません")
 	}
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return nil, err
+	}
+
+	// コメント生成が無効なルームではそのまま返す
+	if !room.Settings.CommentsEnabled {
+		log.Println("コメント生成は無効に設定されています")
+		return room, nil
+	}
 
 	// コメントを生成
 	log.Println("コメントを生成中...")
@@ -206,9 +322,11 @@ func generateJudgingComments(ctx context.Context, args map[string]interface{}) (
 	if room.Topic != nil {
 		topic = *room.Topic
 	}
-	comments, err := generateComments(topic, room.Answers)
+	// コメントは演出のため、生成に失敗しても判定の進行は止めずにコメントなしで保存する
+	comments, err := generateComments(ctx, topic, room.Answers)
 	if err != nil {
-		return nil, fmt.Errorf("コメントの生成に失敗: %w", err)
+		log.Printf("警告: コメントの生成に失敗したためコメントなしで続行: %v", err)
+		comments = []string{}
 	}
 	log.Printf("生成されたコメント数: %d", len(comments))
 
@@ -245,46 +363,161 @@ func generateJudgingComments(ctx context.Context, args map[string]interface{}) (
 	return updatedRoom, nil
 }
 
-// judgeAnswers - 判定結果を保存
-func judgeAnswers(ctx context.Context, args map[string]interface{}) (*JudgeResult, error) {
-	roomID := args["roomId"].(string)
-	isMatch := args["isMatch"].(bool)
-	now := time.Now().UTC().Format(time.RFC3339)
+// requireScoringRule - 判定のフィールドがルームの得点ルールに合うか確認する関数
+// 多数派モードは回答のグループ分けが必要なためjudgeMajorityで、それ以外はjudgeAnswersで判定する
+func requireScoringRule(majority bool) func(room *Room) error {
+	return func(room *Room) error {
+		if room.Settings.ScoringRule == "MAJORITY" && !majority {
+			return fmt.Errorf("多数派モードではjudgeMajorityで判定してください")
+		}
+		if room.Settings.ScoringRule != "MAJORITY" && majority {
+			return fmt.Errorf("多数派モードが有効になっていません")
+		}
+		return nil
+	}
+}
 
-	log.Printf("判定実行: roomId=%s, isMatch=%v", roomID, isMatch)
+// BuildResult - 判定結果を作成
+// 多数派モードは回答のグループ分け（*RoundResult）、チーム戦はチームごとの判定、それ以外は全体の一致（*JudgeResult）
+func (matchingGame) BuildResult(room *Room, args map[string]interface{}) (interface{}, error) {
+	if room.Settings.ScoringRule == "MAJORITY" {
+		return buildMajorityResult(room, args)
+	}
 
-	// 判定結果を保存
-	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(roomTable),
-		Key: map[string]types.AttributeValue{
-			"roomId": &types.AttributeValueMemberS{Value: roomID},
-		},
-		UpdateExpression: aws.String("SET #lastJudgeResult = :lastJudgeResult, #updatedAt = :updatedAt"),
-		ExpressionAttributeNames: map[string]string{
+	// チーム戦ではチームごとに判定する（全チーム一致をお題の一致として扱う）
+	if room.Settings.TeamCount > 0 {
+		return buildTeamResult(room, args)
+	}
+
+	isMatch, ok := args["isMatch"].(bool)
+	if !ok {
+		return nil, fmt.Errorf("isMatchを指定してください")
+	}
+	return &JudgeResult{RoomID: room.RoomID, IsMatch: isMatch}, nil
+}
+
+// Judge - 判定結果と得点を保存し、お題の品質・難易度の記録に加える
+func (g matchingGame) Judge(ctx context.Context, svc *GameServices, room *Room, args map[string]interface{}) (interface{}, error) {
+	built, err := g.BuildResult(room, args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch result := built.(type) {
+	case *RoundResult:
+		return saveMajorityResult(ctx, svc, room, result)
+	case *JudgeResult:
+		if room.Settings.TeamCount > 0 {
+			if err := saveTeamResult(ctx, svc, room, result); err != nil {
+				return nil, err
+			}
+			recordTeamJudgedTopic(ctx, svc, room, result.TeamVerdicts)
+			return result, nil
+		}
+		if err := saveMatchResult(ctx, svc, room, result); err != nil {
+			return nil, err
+		}
+		recordJudgedTopic(ctx, svc, room, result.IsMatch)
+		return result, nil
+	}
+	return nil, fmt.Errorf("不明な判定結果: %T", built)
+}
+
+// saveMatchResult - 全体の一致の判定結果と得点を保存
+func saveMatchResult(ctx context.Context, svc *GameServices, room *Room, result *JudgeResult) error {
+	result.JudgedAt = time.Now().UTC().Format(time.RFC3339)
+	log.Printf("判定実行: roomId=%s, isMatch=%v", room.RoomID, result.IsMatch)
+
+	// 得点の増減を計算（同じラウンドで判定をやり直した場合は前回の判定分を差し引く）
+	scoreDelta := 0
+	if room.Settings.ScoringRule == "ALL_MATCH" {
+		if result.IsMatch {
+			scoreDelta++
+		}
+		if room.LastJudgeResult != nil && *room.LastJudgeResult {
+			scoreDelta--
+		}
+	}
+
+	err := svc.UpdateRoom(ctx, room.RoomID, RoomUpdate{
+		Expression: "SET #lastJudgeResult = :lastJudgeResult, #updatedAt = :updatedAt ADD #score :scoreDelta",
+		Names: map[string]string{
 			"#lastJudgeResult": "lastJudgeResult",
 			"#updatedAt":       "updatedAt",
+			"#score":           "score",
 		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":lastJudgeResult": &types.AttributeValueMemberBOOL{Value: isMatch},
-			":updatedAt":       &types.AttributeValueMemberS{Value: now},
+		Values: map[string]types.AttributeValue{
+			":lastJudgeResult": &types.AttributeValueMemberBOOL{Value: result.IsMatch},
+			":updatedAt":       &types.AttributeValueMemberS{Value: result.JudgedAt},
+			":scoreDelta":      &types.AttributeValueMemberN{Value: strconv.Itoa(scoreDelta)},
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
+		return fmt.Errorf("ルームの更新に失敗: %w", err)
 	}
 
 	log.Println("DynamoDB更新完了")
+	return nil
+}
+
+// recordJudgedTopic - 判定結果をお題の品質・難易度の記録に加える（失敗しても判定は止めない）
+// 判定をやり直した場合は回答が同じため、前回の結果は一致・不一致のみが異なるものとして差し替える
+func recordJudgedTopic(ctx context.Context, svc *GameServices, room *Room, isMatch bool) {
+	if room.Topic == nil {
+		return
+	}
 
-	return &JudgeResult{
-		RoomID:   roomID,
-		IsMatch:  isMatch,
-		JudgedAt: now,
-	}, nil
+	outcome := outcomeFromAnswers(room.Answers, isMatch)
+	var previous *roundOutcome
+	if room.LastJudgeResult != nil {
+		prev := outcome
+		prev.IsMatch = *room.LastJudgeResult
+		previous = &prev
+	}
+	svc.RecordOutcome(ctx, *room.Topic, outcome, previous)
+}
+
+// recordTeamJudgedTopic - チーム戦の判定結果を、チームごとに1ラウンド分としてお題の品質・難易度の記録に加える
+// 全チームの回答をまとめるとチーム間の回答の違いで割れ方が大きく見えるため、各チームの回答とそのチームの判定で記録する
+func recordTeamJudgedTopic(ctx context.Context, svc *GameServices, room *Room, verdicts []TeamVerdict) {
+	if room.Topic == nil {
+		return
+	}
+
+	teams := make(map[string]int)
+	for _, p := range room.Players {
+		teams[p.PlayerID] = p.Team
+	}
+	answersByTeam := make(map[int][]Answer)
+	for _, a := range room.Answers {
+		team := teams[a.PlayerID]
+		answersByTeam[team] = append(answersByTeam[team], a)
+	}
+	previousVerdicts := make(map[int]bool)
+	for _, v := range room.LastTeamVerdicts {
+		previousVerdicts[v.Team] = v.IsMatch
+	}
+
+	for _, v := range verdicts {
+		answers := answersByTeam[v.Team]
+		if len(answers) == 0 {
+			continue
+		}
+		outcome := outcomeFromAnswers(answers, v.IsMatch)
+		var previous *roundOutcome
+		if isMatch, ok := previousVerdicts[v.Team]; ok {
+			prev := outcome
+			prev.IsMatch = isMatch
+			previous = &prev
+		}
+		svc.RecordOutcome(ctx, *room.Topic, outcome, previous)
+	}
 }
 
 // nextRound - 次のラウンドに進む
 func nextRound(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
 	log.Printf("次のラウンド: roomId=%s", roomID)
 
 	// ルーム情報を取得
@@ -295,6 +528,19 @@ func nextRound(ctx context.Context, args map[string]interface{}) (*Room, error)
 	if room == nil {
 		return nil, fmt.Errorf("ルームが見つかりません")
 	}
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return nil, err
+	}
+	if err := requireGameControl(ctx, room, playerID); err != nil {
+		return nil, err
+	}
+
+	// 設定されたラウンド数に達していたら次に進めない
+	if room.Settings.MaxRounds > 0 && room.Round >= room.Settings.MaxRounds {
+		return nil, fmt.Errorf("設定されたラウンド数（%d）に達しました。ゲームを終了してください", room.Settings.MaxRounds)
+	}
+
+	extendRoomTTL(ctx, room)
 
 	// 前ラウンドの回答を削除
 	answers, err := listAnswers(ctx, map[string]interface{}{"roomId": roomID})
@@ -313,55 +559,79 @@ func nextRound(ctx context.Context, args map[string]interface{}) (*Room, error)
 		}
 	}
 
-	topicsPool := room.TopicsPool
+	// プール作成後に評価が下がったお題は出題しない
+	topicsPool := withoutLowQuality(ctx, room.TopicsPool)
 	usedTopics := room.UsedTopics
 
-	// お題プールが空になったら追加生成（5個ずつ）
+	// お題プールが空になったらお題バンクから補充
 	if len(topicsPool) == 0 {
-		log.Println("お題プールが空のため、5個追加生成中...")
-		newTopics, err := generateTopics(usedTopics)
+		log.Println("お題プールが空のため、お題バンクから補充中...")
+		newTopics, err := drawTopics(ctx, room, usedTopics)
 		if err != nil {
-			return nil, fmt.Errorf("お題の生成に失敗: %w", err)
+			return nil, fmt.Errorf("お題の取得に失敗: %w", err)
 		}
-		log.Printf("追加生成されたお題: %v", newTopics)
 		topicsPool = newTopics
 	}
 
-	// 次のお題を取得
-	nextTopic := topicsPool[0]
-	remainingTopics := topicsPool[1:]
-	usedTopics = append(usedTopics, nextTopic)
+	// 次のお題を取得（難易度の指定に合うお題を選ぶ）
+	nextTopic, restTopics := pickNextTopic(ctx, room.Settings, room.Round+1, topicsPool)
+	remainingTopics, err := marshalTopicList(restTopics)
+	if err != nil {
+		return nil, err
+	}
+	usedTopics = append(usedTopics, nextTopic.Text)
 
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	// ルームを更新（判定結果をクリアして次のラウンドへ）
+	names := map[string]string{
+		"#state":            "state",
+		"#topicsPool":       "topicsPool",
+		"#usedTopics":       "usedTopics",
+		"#round":            "round",
+		"#updatedAt":        "updatedAt",
+		"#lastJudgeResult":  "lastJudgeResult",
+		"#lastTeamVerdicts": "lastTeamVerdicts",
+		"#lastRoundResult":  "lastRoundResult",
+		"#judgedAt":         "judgedAt",
+	}
+	values := map[string]types.AttributeValue{
+		":state":      &types.AttributeValueMemberS{Value: "ANSWERING"},
+		":topicsPool": remainingTopics,
+		":usedTopics": marshalStringList(usedTopics),
+		":round":      &types.AttributeValueMemberN{Value: strconv.Itoa(room.Round + 1)},
+		":updatedAt":  &types.AttributeValueMemberS{Value: now},
+	}
+	setTopic, removeTopic := topicUpdate(nextTopic, names, values)
+	setDeadline, removeDeadline := answerDeadlineUpdate(answerDeadline(room.Settings), names, values)
+	expr := "SET #state = :state, " + setTopic + ", #topicsPool = :topicsPool, #usedTopics = :usedTopics, #round = :round, #updatedAt = :updatedAt" + setDeadline + " REMOVE #lastJudgeResult, #lastTeamVerdicts, #lastRoundResult, #judgedAt"
+	if removeTopic != "" {
+		expr += ", " + removeTopic
+	}
+	if removeDeadline != "" {
+		expr += ", " + removeDeadline
+	}
+	expr += queueVersionUpdate(names, values)
+
 	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(roomTable),
 		Key: map[string]types.AttributeValue{
 			"roomId": &types.AttributeValueMemberS{Value: roomID},
 		},
-		UpdateExpression: aws.String("SET #state = :state, #topic = :topic, #topicsPool = :topicsPool, #usedTopics = :usedTopics, #updatedAt = :updatedAt REMOVE #lastJudgeResult, #judgedAt"),
-		ExpressionAttributeNames: map[string]string{
-			"#state":           "state",
-			"#topic":           "topic",
-			"#topicsPool":      "topicsPool",
-			"#usedTopics":      "usedTopics",
-			"#updatedAt":       "updatedAt",
-			"#lastJudgeResult": "lastJudgeResult",
-			"#judgedAt":        "judgedAt",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":state":      &types.AttributeValueMemberS{Value: "ANSWERING"},
-			":topic":      &types.AttributeValueMemberS{Value: nextTopic},
-			":topicsPool": marshalStringList(remainingTopics),
-			":usedTopics": marshalStringList(usedTopics),
-			":updatedAt":  &types.AttributeValueMemberS{Value: now},
-		},
+		UpdateExpression:          aws.String(expr),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
 	}
 
+	recordSeenTopic(ctx, room.Players, nextTopic.Text)
+	maybePrefetchTopics(ctx, roomID, len(restTopics))
+
+	// 前のラウンドの途中に参加したプレイヤーをこのラウンドから参加させる
+	activateWaitingPlayers(ctx, room.Players)
+
 	// 更新後のルーム情報を取得して返す
 	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
 	if err != nil {
@@ -375,6 +645,7 @@ func nextRound(ctx context.Context, args map[string]interface{}) (*Room, error)
 // 回答はクリアせず、お題のみを次に進める
 func skipTopic(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
 	log.Printf("お題スキップ: roomId=%s", roomID)
 
 	// ルーム情報を取得
@@ -385,26 +656,41 @@ func skipTopic(ctx context.Context, args map[string]interface{}) (*Room, error)
 	if room == nil {
 		return nil, fmt.Errorf("ルームが見つかりません")
 	}
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return nil, err
+	}
+	if err := requireGameControl(ctx, room, playerID); err != nil {
+		return nil, err
+	}
+
+	extendRoomTTL(ctx, room)
+
+	// スキップされたことをお題の品質として記録
+	if room.Topic != nil {
+		recordTopicSkip(ctx, *room.Topic)
+	}
 
 	// 現在のお題を使用済みに追加（スキップしたお題も使用済みとする）
-	topicsPool := room.TopicsPool
+	topicsPool := withoutLowQuality(ctx, room.TopicsPool)
 	usedTopics := room.UsedTopics
 
-	// お題プールが空になったら追加生成（5個ずつ）
+	// お題プールが空になったらお題バンクから補充
 	if len(topicsPool) == 0 {
-		log.Println("お題プールが空のため、5個追加生成中...")
-		newTopics, err := generateTopics(usedTopics)
+		log.Println("お題プールが空のため、お題バンクから補充中...")
+		newTopics, err := drawTopics(ctx, room, usedTopics)
 		if err != nil {
-			return nil, fmt.Errorf("お題の生成に失敗: %w", err)
+			return nil, fmt.Errorf("お題の取得に失敗: %w", err)
 		}
-		log.Printf("追加生成されたお題: %v", newTopics)
 		topicsPool = newTopics
 	}
 
-	// 次のお題を取得
-	nextTopic := topicsPool[0]
-	remainingTopics := topicsPool[1:]
-	usedTopics = append(usedTopics, nextTopic)
+	// 次のお題を取得（同じラウンドのため、難易度は現在のラウンドに合わせる）
+	nextTopic, restTopics := pickNextTopic(ctx, room.Settings, room.Round, topicsPool)
+	remainingTopics, err := marshalTopicList(restTopics)
+	if err != nil {
+		return nil, err
+	}
+	usedTopics = append(usedTopics, nextTopic.Text)
 
 	now := time.Now().UTC().Format(time.RFC3339)
 
@@ -425,31 +711,47 @@ func skipTopic(ctx context.Context, args map[string]interface{}) (*Room, error)
 		}
 	}
 
-	// ルームを更新（お題のみ変更、状態はANSWERINGのまま）
+	// ルームを更新（お題と締め切りのみ変更、状態はANSWERINGのまま）
+	names := map[string]string{
+		"#topicsPool": "topicsPool",
+		"#usedTopics": "usedTopics",
+		"#updatedAt":  "updatedAt",
+	}
+	values := map[string]types.AttributeValue{
+		":topicsPool": remainingTopics,
+		":usedTopics": marshalStringList(usedTopics),
+		":updatedAt":  &types.AttributeValueMemberS{Value: now},
+	}
+	setTopic, removeTopic := topicUpdate(nextTopic, names, values)
+	setDeadline, removeDeadline := answerDeadlineUpdate(answerDeadline(room.Settings), names, values)
+	expr := "SET " + setTopic + ", #topicsPool = :topicsPool, #usedTopics = :usedTopics, #updatedAt = :updatedAt" + setDeadline
+	remove := []string{}
+	for _, r := range []string{removeTopic, removeDeadline} {
+		if r != "" {
+			remove = append(remove, r)
+		}
+	}
+	if len(remove) > 0 {
+		expr += " REMOVE " + strings.Join(remove, ", ")
+	}
+	expr += queueVersionUpdate(names, values)
+
 	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(roomTable),
 		Key: map[string]types.AttributeValue{
 			"roomId": &types.AttributeValueMemberS{Value: roomID},
 		},
-		UpdateExpression: aws.String("SET #topic = :topic, #topicsPool = :topicsPool, #usedTopics = :usedTopics, #updatedAt = :updatedAt"),
-		ExpressionAttributeNames: map[string]string{
-			"#topic":      "topic",
-			"#topicsPool": "topicsPool",
-			"#usedTopics": "usedTopics",
-			"#updatedAt":  "updatedAt",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":topic":      &types.AttributeValueMemberS{Value: nextTopic},
-			":topicsPool": marshalStringList(remainingTopics),
-			":usedTopics": marshalStringList(usedTopics),
-			":updatedAt":  &types.AttributeValueMemberS{Value: now},
-		},
+		UpdateExpression:          aws.String(expr),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
 	}
 
-	log.Printf("お題をスキップしました。新しいお題: %s", nextTopic)
+	log.Printf("お題をスキップしました。新しいお題: %s", nextTopic.Text)
+	recordSeenTopic(ctx, room.Players, nextTopic.Text)
+	maybePrefetchTopics(ctx, roomID, len(restTopics))
 
 	// 更新後のルーム情報を取得して返す
 	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
@@ -460,22 +762,37 @@ func skipTopic(ctx context.Context, args map[string]interface{}) (*Room, error)
 	return updatedRoom, nil
 }
 
-// endGame - ゲームを終了
+// endGame - ゲームを終了（ホストのみ、共同ホストは不可）
 func endGame(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
 	now := time.Now().UTC().Format(time.RFC3339)
 
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if room.HostID != playerID {
+		return nil, fmt.Errorf("ホストのみがゲームを終了できます")
+	}
+
 	// 状態をWAITINGに戻す
-	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(roomTable),
 		Key: map[string]types.AttributeValue{
 			"roomId": &types.AttributeValueMemberS{Value: roomID},
 		},
-		UpdateExpression: aws.String("SET #state = :state, #updatedAt = :updatedAt REMOVE #topic"),
+		UpdateExpression: aws.String("SET #state = :state, #updatedAt = :updatedAt REMOVE #topic, #topicCategory, #answerDeadline, #wordWolf"),
 		ExpressionAttributeNames: map[string]string{
-			"#state":     "state",
-			"#topic":     "topic",
-			"#updatedAt": "updatedAt",
+			"#state":          "state",
+			"#topic":          "topic",
+			"#topicCategory":  "topicCategory",
+			"#updatedAt":      "updatedAt",
+			"#answerDeadline": "answerDeadline",
+			"#wordWolf":       "wordWolf",
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":state":     &types.AttributeValueMemberS{Value: "WAITING"},
@@ -486,6 +803,13 @@ func endGame(ctx context.Context, args map[string]interface{}) (*Room, error) {
 		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
 	}
 
+	// 待機状態に戻るため、次のラウンド待ちのプレイヤーも通常の参加状態に戻す
+	players, err := listPlayers(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		log.Printf("警告: プレイヤーの取得に失敗: %v", err)
+	}
+	activateWaitingPlayers(ctx, players)
+
 	// 更新後のルーム情報を取得して返す
 	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
 	if err != nil {
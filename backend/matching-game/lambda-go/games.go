@@ -0,0 +1,227 @@
+// games.go - ゲームの種類ごとの処理を差し替えるためのインターフェースと登録
+// ルーム・プレイヤー管理（room.go）、データ取得（query.go）、TTL（cleanup.go）、OpenAI連携（openai.go）は全ゲームで共通で、
+// 各ゲームは開始・回答の受け付け・結果の作成・判定の保存をGameとして実装し、共通の処理はGameServicesとして受け取る
+// 新しいゲームを追加する場合は、Gameを実装してgamesに登録し、スキーマのGameTypeとフィールドを追加する
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// resolverFunc - GraphQLフィールドを処理する関数（handlerから呼ばれる）
+type resolverFunc func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// Game - ゲームの種類ごとの処理
+// 回答の受け付け・結果の作成・判定の保存はanswerResolver・judgeResolverが共通の確認をしてから呼ぶ
+// 各メソッドはDynamoDB・OpenAI連携のグローバル変数を直接使わず、svcの共通処理を使う
+type Game interface {
+	// Start - startGameから呼ばれ、最初（または次）のラウンドに状態を遷移させる
+	// roomはプレイヤー・回答を結合済みで、最小人数・準備完了の確認とTTLの延長は済んでいる
+	Start(ctx context.Context, svc *GameServices, room *Room) (*Room, error)
+
+	// IsRoundInProgress - ラウンド進行中の状態か（この間に参加したプレイヤーは次のラウンドから参加する）
+	IsRoundInProgress(state string) bool
+
+	// SubmitAnswer - プレイヤーの回答（ワードウルフでは投票）を受け付ける
+	// roomはルーム単体、playerはルームに所属し呼び出し元の本人確認が済んだプレイヤー
+	SubmitAnswer(ctx context.Context, svc *GameServices, room *Room, player *Player, args map[string]interface{}) (interface{}, error)
+
+	// BuildResult - ラウンドの回答（投票）と判定の引数から結果を作成する（保存はしない）
+	// roomはプレイヤー・回答を結合済み
+	BuildResult(room *Room, args map[string]interface{}) (interface{}, error)
+
+	// Judge - BuildResultで作成した結果を保存し、得点・お題の記録に反映する
+	// ホスト・共同ホストの確認は済んでいる（ワードウルフの全員投票による自動公開ではSubmitAnswerから呼ばれる）
+	Judge(ctx context.Context, svc *GameServices, room *Room, args map[string]interface{}) (interface{}, error)
+
+	// Resolvers - このゲーム固有のGraphQLフィールド（回答・判定のフィールドはanswerResolver・judgeResolverで登録する）
+	// 回答・判定以外のフィールドはrequireGameTypeで他のゲームのルームからの呼び出しを拒否すること
+	Resolvers() map[string]resolverFunc
+}
+
+// GameServices - 全ゲームで共通の処理（ルーム・プレイヤー・回答の取得と保存、LLM）
+// ミューテーションが返すGetRoomのルームがサブスクリプションで配信されるため、ゲームは状態の更新後にGetRoomの結果を返す
+type GameServices struct {
+	GetRoom        func(ctx context.Context, roomID string) (*Room, error)                                    // ルームをプレイヤー・回答と結合して取得（存在しない場合はnil）
+	GetRoomItem    func(ctx context.Context, roomID string) (*Room, error)                                    // ルーム単体を取得（存在しない場合はnil）
+	UpdateRoom     func(ctx context.Context, roomID string, update RoomUpdate) error                          // ルームを更新（条件に合わない場合はConditionalCheckFailedExceptionを返す）
+	GetPlayer      func(ctx context.Context, playerID string) (*Player, error)                                // プレイヤーを取得（存在しない場合はnil）
+	AddPlayerScore func(ctx context.Context, playerID string, delta int) error                                // プレイヤーの個人得点を加算
+	SaveAnswer     func(ctx context.Context, answer Answer) error                                             // 回答を保存
+	RequireControl func(ctx context.Context, room *Room, playerID string) error                               // ホスト・共同ホストか確認
+	RecordOutcome  func(ctx context.Context, topic string, outcome roundOutcome, previous *roundOutcome)      // 判定結果をお題の品質・難易度の記録に加える
+	WordPair       func(ctx context.Context, usedPairs []string, categories []string) (string, string, error) // 似ているが異なるお題の組を生成（OpenAI APIが使えない場合は組み込みの組）
+}
+
+// RoomUpdate - ルームの更新式（UpdateItemの式と属性名・値）
+type RoomUpdate struct {
+	Expression string                          // 更新式
+	Condition  string                          // 条件式（空は無条件）
+	Names      map[string]string               // 属性名
+	Values     map[string]types.AttributeValue // 属性値
+}
+
+// gameServices - 各ゲームに渡す共通の処理
+var gameServices = &GameServices{
+	GetRoom: func(ctx context.Context, roomID string) (*Room, error) {
+		return getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	},
+	GetRoomItem:    getRoomItem,
+	UpdateRoom:     updateRoom,
+	GetPlayer:      getPlayerItem,
+	AddPlayerScore: addPlayerScore,
+	SaveAnswer:     saveAnswer,
+	RequireControl: requireGameControl,
+	RecordOutcome:  recordTopicOutcome,
+	WordPair:       generateWordPair,
+}
+
+// games - ゲームの種類（Room.gameType）ごとの実装
+var games = map[string]Game{
+	"MATCHING": matchingGame{}, // 認識合わせ（game.go・teams.go・majority.go）
+	"WORDWOLF": wordWolfGame{}, // ワードウルフ（wordwolf.go）
+}
+
+// gameResolvers - 全ゲームのフィールド名から処理関数への対応（init時に作成）
+var gameResolvers = map[string]resolverFunc{}
+
+// init - 各ゲームのフィールドを登録（同じフィールド名を複数のゲームで使うことはできない）
+func init() {
+	for gameType, game := range games {
+		for field, resolve := range game.Resolvers() {
+			if _, dup := gameResolvers[field]; dup {
+				log.Fatalf("フィールド%sが複数のゲームに登録されています（%s）", field, gameType)
+			}
+			gameResolvers[field] = resolve
+		}
+	}
+}
+
+// answerResolver - 回答のフィールド（submitAnswer・submitVote）の処理関数
+// ゲームの種類と、playerIdのプレイヤーがルームに所属していることを確認してからSubmitAnswerを呼ぶ
+// requireCallerがtrueの場合は、呼び出し元がそのプレイヤーの端末の本人であることも確認する（代理の投票を防ぐワードウルフのみ）
+func answerResolver(gameType string, requireCaller bool) resolverFunc {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		roomID := args["roomId"].(string)
+		playerID := args["playerId"].(string)
+
+		room, err := gameServices.GetRoomItem(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if room == nil {
+			return nil, fmt.Errorf("ルームが見つかりません")
+		}
+		if err := requireGameType(room, gameType); err != nil {
+			return nil, err
+		}
+		var player *Player
+		if requireCaller {
+			player, err = requirePlayerCaller(ctx, room, playerID)
+		} else {
+			player, err = requireRoomPlayer(ctx, room, playerID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return games[gameType].SubmitAnswer(ctx, gameServices, room, player, args)
+	}
+}
+
+// judgeResolver - 判定のフィールド（judgeAnswers・judgeMajority・revealWordWolf）の処理関数
+// ゲームの種類とホスト・共同ホストかを確認し、checkがある場合はフィールド固有の条件も確認してからJudgeを呼ぶ
+func judgeResolver(gameType string, check func(room *Room) error) resolverFunc {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		roomID := args["roomId"].(string)
+		playerID := args["playerId"].(string)
+		log.Printf("判定実行: roomId=%s, gameType=%s", roomID, gameType)
+
+		room, err := gameServices.GetRoom(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		if room == nil {
+			return nil, fmt.Errorf("ルームが見つかりません")
+		}
+		if err := requireGameType(room, gameType); err != nil {
+			return nil, err
+		}
+		if err := gameServices.RequireControl(ctx, room, playerID); err != nil {
+			return nil, err
+		}
+		if check != nil {
+			if err := check(room); err != nil {
+				return nil, err
+			}
+		}
+
+		return games[gameType].Judge(ctx, gameServices, room, args)
+	}
+}
+
+// requireRoomPlayer - playerIdのプレイヤーがルームに所属しているか確認
+func requireRoomPlayer(ctx context.Context, room *Room, playerID string) (*Player, error) {
+	player, err := gameServices.GetPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.RoomID != room.RoomID {
+		return nil, fmt.Errorf("プレイヤーが見つかりません")
+	}
+	return player, nil
+}
+
+// requirePlayerCaller - playerIdのプレイヤーがルームに所属し、呼び出し元がその端末の本人か確認
+// プレイヤーIDはルームのplayersで公開されているため、IDだけでは他のプレイヤーのお題の取得・代理の回答や投票を防げない
+func requirePlayerCaller(ctx context.Context, room *Room, playerID string) (*Player, error) {
+	player, err := requireRoomPlayer(ctx, room, playerID)
+	if err != nil {
+		return nil, err
+	}
+	identityID := callerIdentity(ctx)
+	if identityID == "" || player.IdentityID != identityID {
+		return nil, fmt.Errorf("本人のみが操作できます")
+	}
+	return player, nil
+}
+
+// updateRoom - ルームを更新式で更新
+func updateRoom(ctx context.Context, roomID string, update RoomUpdate) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+		UpdateExpression:          aws.String(update.Expression),
+		ExpressionAttributeNames:  update.Names,
+		ExpressionAttributeValues: update.Values,
+	}
+	if update.Condition != "" {
+		input.ConditionExpression = aws.String(update.Condition)
+	}
+	_, err := ddbClient.UpdateItem(ctx, input)
+	return err
+}
+
+// lookupGame - ゲームの種類から実装を取得
+func lookupGame(gameType string) (Game, error) {
+	game, ok := games[gameType]
+	if !ok {
+		return nil, fmt.Errorf("不明なゲームの種類: %s", gameType)
+	}
+	return game, nil
+}
+
+// resolver - 戻り値の型が異なる処理関数をresolverFuncに変換
+func resolver[T any](f func(ctx context.Context, args map[string]interface{}) (T, error)) resolverFunc {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return f(ctx, args)
+	}
+}
@@ -0,0 +1,255 @@
+// invite.go - 署名付き招待リンク（有効期限・使用回数の上限付き）
+// トークンは「ペイロード（base64url JSON）.署名（HMAC-SHA256）」の形式で、ルームコードを知らなくても参加できる
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInviteExpiresMinutes = 60 * 24     // 招待の既定の有効期間（24時間）
+	maxInviteExpiresMinutes     = 60 * 24 * 7 // 招待の有効期間の上限（7日）
+	maxInviteUsesLimit          = 100         // 使用回数の上限として指定できる最大値
+)
+
+// invitePayload - 招待トークンに署名して埋め込む内容
+type invitePayload struct {
+	RoomID   string `json:"roomId"`   // ルームID
+	InviteID string `json:"inviteId"` // 招待ID（使用回数の記録に使用）
+	Exp      int64  `json:"exp"`      // 有効期限（UNIX秒）
+	MaxUses  int    `json:"maxUses"`  // 使用回数の上限（0は無制限）
+}
+
+// inviteSecret - 署名用のシークレットを取得
+// INVITE_SECRETが未設定の場合は招待リンク自体を無効とする
+func inviteSecret() ([]byte, error) {
+	secret := os.Getenv("INVITE_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("招待リンクは無効化されています")
+	}
+	return []byte(secret), nil
+}
+
+// signInvite - ペイロードに署名してトークンを生成
+func signInvite(payload invitePayload, secret []byte) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("招待のマーシャルに失敗: %w", err)
+	}
+	body := base64.RawURLEncoding.EncodeToString(data)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return body + "." + sig, nil
+}
+
+// verifyInvite - トークンの署名と有効期限を検証してペイロードを返す
+func verifyInvite(token string, secret []byte) (*invitePayload, error) {
+	invalid := errors.New("招待リンクが正しくありません")
+
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, invalid
+	}
+	givenSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, invalid
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	if !hmac.Equal(givenSig, mac.Sum(nil)) {
+		return nil, invalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, invalid
+	}
+	var payload invitePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, invalid
+	}
+
+	if time.Now().Unix() > payload.Exp {
+		return nil, fmt.Errorf("招待リンクの有効期限が切れています")
+	}
+
+	return &payload, nil
+}
+
+// createInvite - ルームの招待リンクを発行（ホストのみ）
+// expiresInMinutes・maxUsesを省略した場合は24時間・無制限
+func createInvite(ctx context.Context, args map[string]interface{}) (*Invite, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+
+	secret, err := inviteSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresInMinutes := defaultInviteExpiresMinutes
+	if v, ok := args["expiresInMinutes"].(float64); ok {
+		expiresInMinutes = int(v)
+	}
+	if expiresInMinutes < 1 || expiresInMinutes > maxInviteExpiresMinutes {
+		return nil, fmt.Errorf("有効期間は1〜%d分で指定してください", maxInviteExpiresMinutes)
+	}
+
+	maxUses := 0
+	if v, ok := args["maxUses"].(float64); ok {
+		maxUses = int(v)
+	}
+	if maxUses < 0 || maxUses > maxInviteUsesLimit {
+		return nil, fmt.Errorf("使用回数の上限は1〜%d回で指定してください（0は無制限）", maxInviteUsesLimit)
+	}
+
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if room.HostID != playerID {
+		return nil, fmt.Errorf("ホストのみが招待リンクを発行できます")
+	}
+	if room.State == "CLOSED" {
+		return nil, fmt.Errorf("クローズされたルームには招待できません")
+	}
+
+	expiresAt := time.Now().UTC().Add(time.Duration(expiresInMinutes) * time.Minute)
+	payload := invitePayload{
+		RoomID:   roomID,
+		InviteID: uuid.New().String(),
+		Exp:      expiresAt.Unix(),
+		MaxUses:  maxUses,
+	}
+
+	token, err := signInvite(payload, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("招待リンク発行: roomId=%s, inviteId=%s, expiresAt=%s, maxUses=%d", roomID, payload.InviteID, expiresAt.Format(time.RFC3339), maxUses)
+
+	return &Invite{
+		Token:     token,
+		RoomID:    roomID,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+		MaxUses:   maxUses,
+	}, nil
+}
+
+// joinRoomByInvite - 招待リンクでルームに参加
+// 招待リンクはパスワードの代わりとなるが、BANの確認と満員時の観戦者扱いは通常の参加と同様
+func joinRoomByInvite(ctx context.Context, args map[string]interface{}) (*Player, error) {
+	token := args["token"].(string)
+	playerName, err := validateName(args["playerName"].(string))
+	if err != nil {
+		return nil, err
+	}
+	deviceToken, _ := args["deviceToken"].(string)
+
+	secret, err := inviteSecret()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := verifyInvite(token, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": payload.RoomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil || room.State == "CLOSED" {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	// 使用回数を消費する前に参加できるか確認（参加できない場合に回数を無駄にしない）
+	if err := checkCanJoin(ctx, room, deviceToken); err != nil {
+		return nil, err
+	}
+	if err := consumeInviteUse(ctx, payload); err != nil {
+		return nil, err
+	}
+
+	log.Printf("招待リンクで参加: roomId=%s, inviteId=%s", payload.RoomID, payload.InviteID)
+
+	return addPlayerToRoom(ctx, room, playerName, deviceToken, joinRole(room))
+}
+
+// consumeInviteUse - 招待の使用回数を1回消費（上限に達している場合はエラー）
+// 回数無制限の招待は記録しない
+func consumeInviteUse(ctx context.Context, payload *invitePayload) error {
+	if payload.MaxUses <= 0 {
+		return nil
+	}
+
+	key := map[string]types.AttributeValue{
+		"roomId": &types.AttributeValueMemberS{Value: payload.RoomID},
+	}
+
+	// 使用回数のマップがない旧データ・初回使用時はマップを作成
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(roomTable),
+		Key:              key,
+		UpdateExpression: aws.String("SET #inviteUses = if_not_exists(#inviteUses, :empty)"),
+		ExpressionAttributeNames: map[string]string{
+			"#inviteUses": "inviteUses",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":empty": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("招待の使用回数の初期化に失敗: %w", err)
+	}
+
+	// 上限未満の場合のみ加算（同時に参加された場合も上限を超えない）
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(roomTable),
+		Key:                 key,
+		UpdateExpression:    aws.String("SET #inviteUses.#inviteId = if_not_exists(#inviteUses.#inviteId, :zero) + :one"),
+		ConditionExpression: aws.String("attribute_not_exists(#inviteUses.#inviteId) OR #inviteUses.#inviteId < :maxUses"),
+		ExpressionAttributeNames: map[string]string{
+			"#inviteUses": "inviteUses",
+			"#inviteId":   payload.InviteID,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":zero":    &types.AttributeValueMemberN{Value: "0"},
+			":one":     &types.AttributeValueMemberN{Value: "1"},
+			":maxUses": &types.AttributeValueMemberN{Value: strconv.Itoa(payload.MaxUses)},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return fmt.Errorf("招待リンクの使用回数の上限に達しています")
+		}
+		return fmt.Errorf("招待の使用回数の更新に失敗: %w", err)
+	}
+
+	return nil
+}
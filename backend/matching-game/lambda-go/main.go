@@ -4,10 +4,29 @@
 // ファイル構成:
 // - main.go    : エントリポイント、初期化、ルーティング、ユーティリティ
 // - models.go  : データ構造体の定義
-// - room.go    : ルーム管理機能（作成・参加・退出・削除）
-// - game.go    : ゲーム進行管理（開始・回答・判定・次ラウンド）
+// - room.go    : ルーム管理機能（作成・参加・退出・追放）
+// - games.go   : ゲームの種類ごとの処理のインターフェースと登録
+// - game.go    : 認識合わせのゲーム進行（開始・回答・判定・次ラウンド）
 // - query.go   : データ取得機能（ルーム・プレイヤー・回答の取得）
 // - openai.go  : OpenAI API連携（お題・コメント生成）
+// - breaker.go : OpenAI API呼び出しのリトライとサーキットブレーカー
+// - fallback.go: OpenAI APIが使えない場合の組み込みのお題
+// - topics.go  : お題プールの保存形式（カテゴリ・想定回答付き）
+// - topicbank.go: 全ルーム共有のお題バンクと端末ごとの閲覧履歴
+// - prefetch.go: お題プールの残りが少なくなった時の非同期補充
+// - quality.go : お題の評価・スキップ・判定結果の記録と評価の低いお題の除外
+// - difficulty.go: 判定結果からのお題の難易度の推定と難易度に合わせた出題
+// - queue.go   : ホストによるお題キューの編集とカスタムのお題の追加・投稿
+// - deck.go    : 名前付きのお題デッキ（JSON・CSVのインポート・エクスポート）
+// - admin.go   : 管理API（ルーム一覧・調査・クローズ・一括削除）
+// - cleanup.go : TTL管理と孤立データの掃除
+// - settings.go: ルーム設定（人数・ラウンド数・制限時間等）
+// - invite.go  : 署名付き招待リンク（発行・招待での参加）
+// - teams.go   : チーム戦（チーム分け・チームごとの判定と得点）
+// - majority.go: 多数派モード（回答のグループ分けと個人得点）
+// - wordwolf.go: ワードウルフ（お題の配布・議論・投票・結果公開）
+// - topicgen/  : お題の生成ルール（プロンプト・検証）とデッキファイルの形式（cmd/topicgenと共通）
+// - cmd/topicgen: お題をオフラインで事前生成・精査するCLI（Lambdaには含まれない）
 package main
 
 import (
@@ -33,6 +52,11 @@ var (
 	roomTable   string           // ルームテーブル名
 	playerTable string           // プレイヤーテーブル名
 	answerTable string           // 回答テーブル名
+
+	topicBankTable    string // お題バンクテーブル名（全ルーム共有）
+	topicHistoryTable string // 端末ごとのお題の閲覧履歴テーブル名
+	topicStatsTable   string // お題ごとの品質の記録テーブル名
+	topicDeckTable    string // 名前付きのお題デッキテーブル名
 )
 
 // ===========================================
@@ -46,6 +70,10 @@ func init() {
 	roomTable = os.Getenv("ROOM_TABLE")
 	playerTable = os.Getenv("PLAYER_TABLE")
 	answerTable = os.Getenv("ANSWER_TABLE")
+	topicBankTable = os.Getenv("TOPIC_BANK_TABLE")
+	topicHistoryTable = os.Getenv("TOPIC_HISTORY_TABLE")
+	topicStatsTable = os.Getenv("TOPIC_STATS_TABLE")
+	topicDeckTable = os.Getenv("TOPIC_DECK_TABLE")
 
 	// AWS SDK設定を読み込み
 	cfg, err := config.LoadDefaultConfig(context.Background())
@@ -56,6 +84,9 @@ func init() {
 	// DynamoDBクライアントを初期化
 	ddbClient = dynamodb.NewFromConfig(cfg)
 
+	// お題プールの補充の依頼先を初期化
+	prefetcher = newTopicPrefetcher(cfg)
+
 	// 乱数シードを初期化（ルームコード生成用）
 	rand.Seed(time.Now().UnixNano())
 }
@@ -72,9 +103,12 @@ func main() {
 // handler - AppSyncからのリクエストを処理するメインハンドラー
 // GraphQLのフィールド名に応じて適切な関数にルーティングする
 func handler(ctx context.Context, event AppSyncEvent) (interface{}, error) {
-	log.Printf("イベント受信: %+v", event)
+	// 引数には管理者シークレット・参加パスワードが含まれるため、イベントはそのまま出力せず伏せた引数のみ出力する
 	log.Printf("フィールド名: %s", event.Info.FieldName)
-	log.Printf("引数: %+v", event.Arguments)
+	log.Printf("引数: %+v", redactArgs(event.Arguments))
+
+	// 呼び出し元の認証情報をコンテキストに格納（BAN判定等で使用）
+	ctx = withCallerIdentity(ctx, event.Identity)
 
 	// フィールド名に応じて処理を振り分け
 	switch event.Info.FieldName {
@@ -84,31 +118,50 @@ func handler(ctx context.Context, event AppSyncEvent) (interface{}, error) {
 		return createRoom(ctx, event.Arguments)
 	case "joinRoom":
 		return joinRoom(ctx, event.Arguments)
+	case "joinAsSpectator":
+		return joinAsSpectator(ctx, event.Arguments)
 	case "leaveRoom":
 		return leaveRoom(ctx, event.Arguments)
 	case "kickPlayer":
 		return kickPlayer(ctx, event.Arguments)
-	case "deleteAllData":
-		return deleteAllData(ctx)
+	case "unbanPlayer":
+		return unbanPlayer(ctx, event.Arguments)
+	case "setReady":
+		return setReady(ctx, event.Arguments)
+	case "setCohost":
+		return setCohost(ctx, event.Arguments)
+	case "renamePlayer":
+		return renamePlayer(ctx, event.Arguments)
+	case "updateRoomSettings":
+		return updateRoomSettings(ctx, event.Arguments)
+
+	// 招待リンク (invite.go)
+	case "createInvite":
+		return createInvite(ctx, event.Arguments)
+	case "joinRoomByInvite":
+		return joinRoomByInvite(ctx, event.Arguments)
 
-	// ゲーム進行 (game.go)
+	// お題デッキ (deck.go)
+	case "importTopicDeck":
+		return importTopicDeck(ctx, event.Arguments)
+	case "deleteTopicDeck":
+		return deleteTopicDeck(ctx, event.Arguments)
+
+	// ゲーム進行 (game.go、全ゲーム共通)
+	// 回答・判定・チーム分け等のゲーム固有のフィールドは各ゲームのResolversで登録する（games.go）
 	case "startGame":
 		return startGame(ctx, event.Arguments)
-	case "submitAnswer":
-		return submitAnswer(ctx, event.Arguments)
-	case "startJudging":
-		return startJudging(ctx, event.Arguments)
-	case "generateJudgingComments":
-		return generateJudgingComments(ctx, event.Arguments)
-	case "judgeAnswers":
-		return judgeAnswers(ctx, event.Arguments)
-	case "nextRound":
-		return nextRound(ctx, event.Arguments)
-	case "skipTopic":
-		return skipTopic(ctx, event.Arguments)
 	case "endGame":
 		return endGame(ctx, event.Arguments)
 
+	// 管理API (admin.go)
+	case "closeRoom":
+		return closeRoom(ctx, event.Arguments)
+	case "purgeRoomsOlderThan":
+		return purgeRoomsOlderThan(ctx, event.Arguments)
+	case "cleanupOrphans":
+		return cleanupOrphans(ctx, event.Arguments)
+
 	// ========== Query（データ取得操作） ==========
 	// データ取得 (query.go)
 	case "getRoom":
@@ -119,8 +172,36 @@ func handler(ctx context.Context, event AppSyncEvent) (interface{}, error) {
 		return listPlayers(ctx, event.Arguments)
 	case "listAnswers":
 		return listAnswers(ctx, event.Arguments)
+	case "listPublicRooms":
+		return listPublicRooms(ctx, event.Arguments)
+
+	// お題デッキ (deck.go)
+	case "listTopicDecks":
+		return listTopicDecks(ctx, event.Arguments)
+	case "exportTopicDeck":
+		return exportTopicDeck(ctx, event.Arguments)
+
+	// 管理API (admin.go)
+	case "listActiveRooms":
+		return listActiveRooms(ctx, event.Arguments)
+	case "inspectRoom":
+		return inspectRoom(ctx, event.Arguments)
+
+	// ========== 定期実行（EventBridgeからの直接呼び出し） ==========
+	case "scheduledCleanup":
+		return scheduledCleanup(ctx)
+	case "scheduledTopicRefill":
+		return scheduledTopicRefill(ctx)
+
+	// ========== 非同期処理（Lambdaの自己呼び出し） ==========
+	case "prefetchTopics":
+		return prefetchTopics(ctx, event.Arguments)
 
 	default:
+		// ゲーム固有のフィールド（games.go）
+		if resolve, ok := gameResolvers[event.Info.FieldName]; ok {
+			return resolve(ctx, event.Arguments)
+		}
 		return nil, fmt.Errorf("不明なフィールド: %s", event.Info.FieldName)
 	}
 }
@@ -129,11 +210,78 @@ func handler(ctx context.Context, event AppSyncEvent) (interface{}, error) {
 // ユーティリティ関数
 // ===========================================
 
+// secretArgKeys - ログに出力しない引数の名前
+var secretArgKeys = map[string]bool{
+	"adminSecret": true, // 管理者シークレット
+	"password":    true, // ルームの参加パスワード（joinRoomのpassword・settings.password）
+}
+
+// redactArgs - 秘密の引数の値を伏せた引数のコピーを作成（ログ出力用、入れ子の入力も対象）
+func redactArgs(args map[string]interface{}) map[string]interface{} {
+	safe := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if secretArgKeys[k] {
+			safe[k] = "***"
+			continue
+		}
+		safe[k] = redactValue(v)
+	}
+	return safe
+}
+
+// redactValue - 入力オブジェクト・リストの中の秘密の引数を伏せる
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return redactArgs(v)
+	case []interface{}:
+		safe := make([]interface{}, len(v))
+		for i, item := range v {
+			safe[i] = redactValue(item)
+		}
+		return safe
+	}
+	return v
+}
+
+// callerIdentityKey - コンテキストに呼び出し元IDを格納するためのキー
+type callerIdentityKey struct{}
+
+// withCallerIdentity - 呼び出し元のCognito Identity IDをコンテキストに格納
+func withCallerIdentity(ctx context.Context, identity *AppSyncIdentity) context.Context {
+	if identity == nil || identity.CognitoIdentityID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, callerIdentityKey{}, identity.CognitoIdentityID)
+}
+
+// callerIdentity - コンテキストから呼び出し元のCognito Identity IDを取得（不明な場合は空文字）
+func callerIdentity(ctx context.Context) string {
+	id, _ := ctx.Value(callerIdentityKey{}).(string)
+	return id
+}
+
 // generateRoomCode - 6桁のランダムなルームコードを生成
 func generateRoomCode() string {
 	return fmt.Sprintf("%06d", rand.Intn(900000)+100000)
 }
 
+// generateUniqueRoomCode - 稼働中のルームと重複しないルームコードを生成
+// クローズされたルームのコードはGSIから外れているため再利用される
+func generateUniqueRoomCode(ctx context.Context) (string, error) {
+	for i := 0; i < 10; i++ {
+		code := generateRoomCode()
+		existing, err := getRoomByCode(ctx, map[string]interface{}{"roomCode": code})
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("ルームコードの生成に失敗しました")
+}
+
 // marshalStringList - 文字列配列をDynamoDB用の属性値に変換
 func marshalStringList(list []string) types.AttributeValue {
 	if len(list) == 0 {
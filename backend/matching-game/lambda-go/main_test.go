@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := map[string]interface{}{
+		"roomId":      "room-1",
+		"adminSecret": "secret",
+		"password":    "pass",
+		"input": map[string]interface{}{
+			"password":    "pass",
+			"adminSecret": "secret",
+			"items":       []interface{}{map[string]interface{}{"adminSecret": "secret", "n": 1.0}},
+		},
+	}
+	want := map[string]interface{}{
+		"roomId":      "room-1",
+		"adminSecret": "***",
+		"password":    "***",
+		"input": map[string]interface{}{
+			"password":    "***",
+			"adminSecret": "***",
+			"items":       []interface{}{map[string]interface{}{"adminSecret": "***", "n": 1.0}},
+		},
+	}
+
+	if got := redactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if args["adminSecret"] != "secret" || args["input"].(map[string]interface{})["adminSecret"] != "secret" {
+		t.Error("redactArgsが元の引数を書き換えた")
+	}
+}
@@ -0,0 +1,267 @@
+// majority.go - 多数派モード（最も人数の多い回答グループのメンバーが得点する）
+// settings.scoringRuleがMAJORITYの場合、judgeAnswersの代わりにjudgeMajorityで判定する
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// validTieRules - 最大グループが同数の場合の扱い
+var validTieRules = map[string]bool{
+	"ALL":  true, // 同数の最大グループ全員が得点
+	"NONE": true, // 同数の場合は誰も得点しない
+}
+
+// defaultTieRule - 同数時のルールの既定値
+const defaultTieRule = "ALL"
+
+// buildMajorityResult - 回答をグループ分けし、最大グループのメンバーを得点するグループとした判定結果を作成（judgeMajority）
+// groupsで回答IDの組を指定すると、表記が異なる回答も同じ答えとしてまとめる
+// 指定されなかった回答は表記の正規化（全角半角・大文字小文字・空白）で自動的にまとめる
+func buildMajorityResult(room *Room, args map[string]interface{}) (*RoundResult, error) {
+	manualGroups, err := parseAnswerGroups(args["groups"])
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupAnswers(room.Answers, manualGroups)
+	markScoredGroups(groups, room.Settings.TieRule)
+
+	return &RoundResult{
+		RoomID:  room.RoomID,
+		Round:   room.Round,
+		Groups:  groups,
+		TieRule: room.Settings.TieRule,
+	}, nil
+}
+
+// saveMajorityResult - 多数派判定の結果を保存し、最大グループのメンバーに得点を与える
+func saveMajorityResult(ctx context.Context, svc *GameServices, room *Room, result *RoundResult) (*RoundResult, error) {
+	result.JudgedAt = time.Now().UTC().Format(time.RFC3339)
+
+	// 同じラウンドで判定をやり直した場合は前回の得点を差し引く
+	deltas := make(map[string]int)
+	if room.LastRoundResult != nil {
+		for _, id := range scoredPlayerIDs(room.LastRoundResult.Groups) {
+			deltas[id]--
+		}
+	}
+	for _, id := range scoredPlayerIDs(result.Groups) {
+		deltas[id]++
+	}
+	for id, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		if err := svc.AddPlayerScore(ctx, id, delta); err != nil {
+			log.Printf("警告: プレイヤーの得点更新に失敗 %s: %v", id, err)
+		}
+	}
+
+	resultItem, err := attributevalue.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("判定結果のマーシャルに失敗: %w", err)
+	}
+
+	err = svc.UpdateRoom(ctx, room.RoomID, RoomUpdate{
+		Expression: "SET #lastRoundResult = :lastRoundResult, #updatedAt = :updatedAt",
+		Names: map[string]string{
+			"#lastRoundResult": "lastRoundResult",
+			"#updatedAt":       "updatedAt",
+		},
+		Values: map[string]types.AttributeValue{
+			":lastRoundResult": resultItem,
+			":updatedAt":       &types.AttributeValueMemberS{Value: result.JudgedAt},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
+	}
+
+	// 判定結果をお題の品質・難易度の記録に加える（やり直しの場合は前回の結果と差し替える）
+	if room.Topic != nil {
+		var previous *roundOutcome
+		if room.LastRoundResult != nil {
+			prev := outcomeFromGroups(room.LastRoundResult.Groups)
+			previous = &prev
+		}
+		svc.RecordOutcome(ctx, *room.Topic, outcomeFromGroups(result.Groups), previous)
+	}
+
+	log.Printf("多数派判定完了: roomId=%s, groups=%d", room.RoomID, len(result.Groups))
+
+	return result, nil
+}
+
+// parseAnswerGroups - judgeMajorityのgroups引数（回答IDのリストのリスト）を取得
+func parseAnswerGroups(input interface{}) ([][]string, error) {
+	list, ok := input.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	groups := [][]string{}
+	for _, item := range list {
+		ids, ok := item.([]interface{})
+		if !ok {
+			continue
+		}
+		group := []string{}
+		for _, v := range ids {
+			id, ok := v.(string)
+			if !ok {
+				continue
+			}
+			if seen[id] {
+				return nil, fmt.Errorf("回答%sが複数のグループに含まれています", id)
+			}
+			seen[id] = true
+			group = append(group, id)
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// groupAnswers - 回答をグループ分け（人数の多い順、同数の場合は最初の回答の提出順）
+func groupAnswers(answers []Answer, manualGroups [][]string) []AnswerGroup {
+	// 提出順に並べて、代表の回答とグループの順序を安定させる
+	sorted := make([]Answer, len(answers))
+	copy(sorted, answers)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SubmittedAt < sorted[j].SubmittedAt })
+
+	// 手動で指定された回答IDは、そのグループの先頭の回答IDをキーにする
+	manualKey := make(map[string]string)
+	for _, group := range manualGroups {
+		for _, id := range group {
+			manualKey[id] = "manual:" + group[0]
+		}
+	}
+
+	index := make(map[string]int)
+	groups := []AnswerGroup{}
+	for _, answer := range sorted {
+		text := ""
+		if answer.TextAnswer != nil {
+			text = *answer.TextAnswer
+		}
+		key, ok := manualKey[answer.AnswerID]
+		if !ok {
+			key = "text:" + normalizeName(text)
+		}
+
+		member := GroupMember{
+			PlayerID:   answer.PlayerID,
+			PlayerName: answer.PlayerName,
+			AnswerID:   answer.AnswerID,
+		}
+		if i, ok := index[key]; ok {
+			groups[i].Members = append(groups[i].Members, member)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, AnswerGroup{
+			Answer:  text,
+			Members: []GroupMember{member},
+		})
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Members) > len(groups[j].Members) })
+	return groups
+}
+
+// markScoredGroups - 最大グループに得点フラグを付ける
+// 1人だけのグループは一致とみなさないため得点しない
+func markScoredGroups(groups []AnswerGroup, tieRule string) {
+	if len(groups) == 0 || len(groups[0].Members) < 2 {
+		return
+	}
+
+	largest := len(groups[0].Members)
+	tied := 0
+	for _, g := range groups {
+		if len(g.Members) == largest {
+			tied++
+		}
+	}
+	if tied > 1 && tieRule == "NONE" {
+		return
+	}
+
+	for i := range groups {
+		if len(groups[i].Members) == largest {
+			groups[i].Scored = true
+		}
+	}
+}
+
+// scoredPlayerIDs - 得点したグループのプレイヤーID一覧
+func scoredPlayerIDs(groups []AnswerGroup) []string {
+	ids := []string{}
+	for _, g := range groups {
+		if !g.Scored {
+			continue
+		}
+		for _, m := range g.Members {
+			ids = append(ids, m.PlayerID)
+		}
+	}
+	return ids
+}
+
+// addPlayerScore - プレイヤーの個人得点を加算
+func addPlayerScore(ctx context.Context, playerID string, delta int) error {
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(playerTable),
+		Key: map[string]types.AttributeValue{
+			"playerId": &types.AttributeValueMemberS{Value: playerID},
+		},
+		UpdateExpression:    aws.String("ADD #score :delta"),
+		ConditionExpression: aws.String("attribute_exists(playerId)"),
+		ExpressionAttributeNames: map[string]string{
+			"#score": "score",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":delta": &types.AttributeValueMemberN{Value: strconv.Itoa(delta)},
+		},
+	})
+	return err
+}
+
+// resetPlayerScores - 全プレイヤーの個人得点を0に戻す（ゲーム開始時に呼ぶ）
+func resetPlayerScores(ctx context.Context, players []Player) {
+	for _, p := range players {
+		if p.Score == 0 {
+			continue
+		}
+		_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(playerTable),
+			Key: map[string]types.AttributeValue{
+				"playerId": &types.AttributeValueMemberS{Value: p.PlayerID},
+			},
+			UpdateExpression: aws.String("SET #score = :zero"),
+			ExpressionAttributeNames: map[string]string{
+				"#score": "score",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":zero": &types.AttributeValueMemberN{Value: "0"},
+			},
+		})
+		if err != nil {
+			log.Printf("警告: 得点のリセットに失敗 %s: %v", p.PlayerID, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// textAnswer - テスト用の回答を作成
+func textAnswer(id, playerID, text, submittedAt string) Answer {
+	return Answer{AnswerID: id, PlayerID: playerID, PlayerName: "name-" + playerID, TextAnswer: &text, SubmittedAt: submittedAt}
+}
+
+// groupSummary - グループの代表の回答とメンバーの回答IDを取り出す
+func groupSummary(groups []AnswerGroup) map[string][]string {
+	summary := make(map[string][]string)
+	for _, g := range groups {
+		var ids []string
+		for _, m := range g.Members {
+			ids = append(ids, m.AnswerID)
+		}
+		summary[g.Answer] = ids
+	}
+	return summary
+}
+
+func TestGroupAnswers(t *testing.T) {
+	answers := []Answer{
+		textAnswer("a3", "p3", "ｶﾚｰ", "2024-01-01T00:00:03Z"),
+		textAnswer("a1", "p1", "Ｃｕｒｒｙ　Rice", "2024-01-01T00:00:01Z"),
+		textAnswer("a2", "p2", " curry  rice ", "2024-01-01T00:00:02Z"),
+		textAnswer("a4", "p4", "ラーメン", "2024-01-01T00:00:04Z"),
+		textAnswer("a5", "p5", "CURRY RICE", "2024-01-01T00:00:05Z"),
+	}
+
+	tests := []struct {
+		name         string
+		manualGroups [][]string
+		wantOrder    []string
+		want         map[string][]string
+	}{
+		{
+			name:      "正規化した回答でまとめる",
+			wantOrder: []string{"Ｃｕｒｒｙ　Rice", "ｶﾚｰ", "ラーメン"},
+			want: map[string][]string{
+				"Ｃｕｒｒｙ　Rice": {"a1", "a2", "a5"},
+				"ｶﾚｰ":        {"a3"},
+				"ラーメン":       {"a4"},
+			},
+		},
+		{
+			name:         "手動のグループを優先する",
+			manualGroups: [][]string{{"a3", "a4", "a5"}},
+			wantOrder:    []string{"ｶﾚｰ", "Ｃｕｒｒｙ　Rice"},
+			want: map[string][]string{
+				"Ｃｕｒｒｙ　Rice": {"a1", "a2"},
+				"ｶﾚｰ":        {"a3", "a4", "a5"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := groupAnswers(answers, tt.manualGroups)
+
+			var order []string
+			for _, g := range groups {
+				order = append(order, g.Answer)
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("順序 = %q, want %q", order, tt.wantOrder)
+			}
+			if got := groupSummary(groups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("グループ = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if groups := groupAnswers(nil, nil); len(groups) != 0 {
+		t.Errorf("回答なし: %v", groups)
+	}
+	if answers[0].AnswerID != "a3" {
+		t.Error("groupAnswersが引数の回答を並べ替えた")
+	}
+}
+
+func TestGroupAnswersMembers(t *testing.T) {
+	groups := groupAnswers([]Answer{textAnswer("a1", "p1", "いぬ", "2024-01-01T00:00:01Z")}, nil)
+	want := []AnswerGroup{{Answer: "いぬ", Members: []GroupMember{{PlayerID: "p1", PlayerName: "name-p1", AnswerID: "a1"}}}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("got %+v, want %+v", groups, want)
+	}
+}
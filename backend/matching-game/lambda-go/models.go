@@ -1,6 +1,8 @@
 // models.go - データ構造体の定義
 package main
 
+import "mitsu-game-lambda/topicgen"
+
 // ===========================================
 // AppSync イベント構造体
 // ===========================================
@@ -9,6 +11,7 @@ package main
 type AppSyncEvent struct {
 	Info      AppSyncInfo            `json:"info"`      // GraphQL操作情報
 	Arguments map[string]interface{} `json:"arguments"` // 引数
+	Identity  *AppSyncIdentity       `json:"identity"`  // 呼び出し元の認証情報（IAM認証時のみ）
 }
 
 // AppSyncInfo - GraphQL操作の詳細情報
@@ -16,38 +19,183 @@ type AppSyncInfo struct {
 	FieldName string `json:"fieldName"` // 呼び出されたフィールド名（createRoom, joinRoom等）
 }
 
+// AppSyncIdentity - IAM認証（Cognito Identity Pool）の呼び出し元情報
+type AppSyncIdentity struct {
+	CognitoIdentityID string `json:"cognitoIdentityId"` // Cognito Identity ID（端末ごとのゲストID）
+}
+
 // ===========================================
 // ドメインモデル（データ構造）
 // ===========================================
 
 // Room - ゲームルーム情報
 type Room struct {
-	RoomID          string   `json:"roomId" dynamodbav:"roomId"`                                        // ルームID（UUID）
-	RoomCode        string   `json:"roomCode" dynamodbav:"roomCode"`                                    // ルームコード（6桁数字）
-	HostID          string   `json:"hostId" dynamodbav:"hostId"`                                        // ホストのプレイヤーID
-	State           string   `json:"state" dynamodbav:"state"`                                          // ゲーム状態（WAITING/ANSWERING/JUDGING）
-	Topic           *string  `json:"topic" dynamodbav:"topic,omitempty"`                                // 現在のお題
-	TopicsPool      []string `json:"topicsPool" dynamodbav:"topicsPool"`                                // 未使用のお題プール
-	UsedTopics      []string `json:"usedTopics" dynamodbav:"usedTopics"`                                // 使用済みお題リスト
-	LastJudgeResult *bool    `json:"lastJudgeResult,omitempty" dynamodbav:"lastJudgeResult,omitempty"`  // 前回の判定結果
-	JudgedAt        *string  `json:"judgedAt,omitempty" dynamodbav:"judgedAt,omitempty"`                // 判定日時
-	Comments        []string `json:"comments,omitempty" dynamodbav:"comments,omitempty"`                // ニコニコ風コメント
-	CreatedAt       string   `json:"createdAt" dynamodbav:"createdAt"`                                  // 作成日時
-	UpdatedAt       string   `json:"updatedAt" dynamodbav:"updatedAt"`                                  // 更新日時
-	TTL             int64    `json:"ttl" dynamodbav:"ttl"`                                              // TTL（24時間後に自動削除）
-	Players         []Player `json:"players"`                                                           // プレイヤー一覧（結合データ）
-	Answers         []Answer `json:"answers"`                                                           // 回答一覧（結合データ）
+	RoomID           string            `json:"roomId" dynamodbav:"roomId"`                                       // ルームID（UUID）
+	RoomCode         string            `json:"roomCode" dynamodbav:"roomCode"`                                   // ルームコード（6桁数字）
+	HostID           string            `json:"hostId" dynamodbav:"hostId"`                                       // ホストのプレイヤーID
+	GameType         string            `json:"gameType" dynamodbav:"gameType,omitempty"`                         // ゲームの種類（MATCHING/WORDWOLF、未設定の旧データはMATCHING）
+	State            string            `json:"state" dynamodbav:"state"`                                         // ゲーム状態（WAITING/ANSWERING/JUDGING/DISCUSSING/VOTING/REVEALED/CLOSED）
+	Topic            *string           `json:"topic" dynamodbav:"topic,omitempty"`                               // 現在のお題
+	TopicCategory    *string           `json:"topicCategory" dynamodbav:"topicCategory,omitempty"`               // 現在のお題のカテゴリ
+	TopicsPool       []Topic           `json:"-" dynamodbav:"topicsPool"`                                        // 未使用のお題プール（カテゴリ・想定回答付き）
+	TopicsPoolTexts  []string          `json:"topicsPool" dynamodbav:"-"`                                        // 未使用のお題の文のみ（レスポンス用、想定回答は返さない）
+	UsedTopics       []string          `json:"usedTopics" dynamodbav:"usedTopics"`                               // 使用済みお題リスト
+	UsedWordPairs    []string          `json:"-" dynamodbav:"usedWordPairs,omitempty"`                           // ワードウルフの使用済みお題の組（「多数派/少数派」、非公開）
+	TopicSubmissions []TopicSubmission `json:"-" dynamodbav:"topicSubmissions,omitempty"`                        // プレイヤーが投稿した承認待ちのお題（ホストのみgetTopicQueueで取得）
+	QueueVersion     int               `json:"-" dynamodbav:"queueVersion,omitempty"`                            // お題プール・投稿の版（書き換えるたびに加算、キューの保存の競合検出用）
+	LastJudgeResult  *bool             `json:"lastJudgeResult,omitempty" dynamodbav:"lastJudgeResult,omitempty"` // 前回の判定結果
+	JudgedAt         *string           `json:"judgedAt,omitempty" dynamodbav:"judgedAt,omitempty"`               // 判定日時
+	Comments         []string          `json:"comments,omitempty" dynamodbav:"comments,omitempty"`               // ニコニコ風コメント
+	CreatedAt        string            `json:"createdAt" dynamodbav:"createdAt"`                                 // 作成日時
+	UpdatedAt        string            `json:"updatedAt" dynamodbav:"updatedAt"`                                 // 更新日時
+	ClosedAt         *string           `json:"closedAt,omitempty" dynamodbav:"closedAt,omitempty"`               // クローズ日時（全員退出時）
+	BanList          []Ban             `json:"banList" dynamodbav:"banList,omitempty"`                           // 追放されたプレイヤーの一覧
+	Visibility       string            `json:"visibility" dynamodbav:"visibility,omitempty"`                     // 公開設定（PRIVATE/PUBLIC、GSIのキー）
+	PasswordHash     string            `json:"-" dynamodbav:"passwordHash,omitempty"`                            // 参加パスワードのハッシュ（非公開）
+	PasswordSalt     string            `json:"-" dynamodbav:"passwordSalt,omitempty"`                            // パスワードハッシュのソルト（非公開）
+	HasPassword      bool              `json:"hasPassword" dynamodbav:"-"`                                       // パスワードが設定されているか（レスポンス用）
+	Settings         *RoomSettings     `json:"settings" dynamodbav:"settings,omitempty"`                         // ルーム設定（未設定の旧データは既定値で補完）
+	Round            int               `json:"round" dynamodbav:"round"`                                         // 現在のラウンド番号（開始前は0）
+	Score            int               `json:"score" dynamodbav:"score"`                                         // 得点（得点ルールに従って加算）
+	TeamScores       []TeamScore       `json:"teamScores" dynamodbav:"teamScores,omitempty"`                     // チームごとの得点（チーム戦のみ）
+	LastRoundResult  *RoundResult      `json:"lastRoundResult" dynamodbav:"lastRoundResult,omitempty"`           // 現在のラウンドの多数派判定の結果（多数派モードのみ）
+	LastTeamVerdicts []TeamVerdict     `json:"lastTeamVerdicts" dynamodbav:"lastTeamVerdicts,omitempty"`         // 前回のチームごとの判定結果（チーム戦のみ）
+	WordWolf         *WordWolfGame     `json:"wordWolf" dynamodbav:"wordWolf,omitempty"`                         // ワードウルフの進行状況（ワードウルフのみ）
+	AnswerDeadline   *string           `json:"answerDeadline,omitempty" dynamodbav:"answerDeadline,omitempty"`   // 回答締め切り（制限時間ありの場合）
+	TTL              int64             `json:"ttl" dynamodbav:"ttl"`                                             // TTL（最後の活動から24時間後に自動削除）
+	InviteUses       map[string]int    `json:"-" dynamodbav:"inviteUses,omitempty"`                              // 招待リンクごとの使用回数（回数制限付きの招待のみ、非公開）
+	Players          []Player          `json:"players"`                                                          // プレイヤー一覧（結合データ）
+	Answers          []Answer          `json:"answers"`                                                          // 回答一覧（結合データ）
+}
+
+// Topic - お題（プールに保存する単位、生成したお題はtopicgen.Topicから変換する）
+type Topic struct {
+	Text           string   `json:"topic" dynamodbav:"text"`                    // お題の文（「〜といえば？」）
+	Category       string   `json:"category" dynamodbav:"category"`             // カテゴリ（topicgen.Categoriesのいずれか）
+	ExampleAnswers []string `json:"exampleAnswers" dynamodbav:"exampleAnswers"` // 想定される回答の例（1〜3個）
+	Source         string   `json:"-" dynamodbav:"source,omitempty"`            // お題の出典（CUSTOM: ホストが追加、DECK: デッキ、空: 生成・バンク）
+	SubmittedBy    string   `json:"-" dynamodbav:"submittedBy,omitempty"`       // お題を考えたプレイヤー名（プレイヤーの投稿を追加した場合のみ）
+	Pinned         bool     `json:"-" dynamodbav:"pinned,omitempty"`            // ホストが位置を指定したお題（先頭に来たら難易度で選び直さずに出題する）
+	Difficulty     string   `json:"-" dynamodbav:"difficulty,omitempty"`        // デッキで指定された難易度（判定の記録が少ない間の見積もりに使う）
+}
+
+// TopicDeck - 名前付きのお題デッキ（インポートしたお題の集合、ルームのお題の出典として選択できる）
+type TopicDeck struct {
+	DeckID          string               `json:"deckId" dynamodbav:"deckId"`               // デッキID（UUID）
+	Name            string               `json:"name" dynamodbav:"name"`                   // デッキ名
+	Description     string               `json:"description" dynamodbav:"description"`     // 説明
+	TopicCount      int                  `json:"topicCount" dynamodbav:"topicCount"`       // お題の数
+	Languages       []string             `json:"languages" dynamodbav:"languages"`         // 含まれるお題の言語
+	Tags            []string             `json:"tags" dynamodbav:"tags"`                   // 含まれるお題のタグ
+	CreatedAt       string               `json:"createdAt" dynamodbav:"createdAt"`         // 作成日時
+	UpdatedAt       string               `json:"updatedAt" dynamodbav:"updatedAt"`         // 更新日時
+	OwnerIdentityID string               `json:"-" dynamodbav:"ownerIdentityId,omitempty"` // 作成した端末のCognito Identity ID（非公開）
+	Topics          []topicgen.DeckTopic `json:"-" dynamodbav:"topics,omitempty"`          // お題（exportTopicDeckでのみ返す）
+}
+
+// TopicDeckImportResult - デッキのインポート結果
+type TopicDeckImportResult struct {
+	Deck     *TopicDeck `json:"deck"`     // インポート後のデッキ
+	Imported int        `json:"imported"` // 取り込んだお題の数
+	Skipped  []string   `json:"skipped"`  // 取り込まなかった行と理由
+}
+
+// TopicSubmission - プレイヤーが投稿し、ホストの承認を待っているお題
+type TopicSubmission struct {
+	SubmissionID string  `json:"submissionId" dynamodbav:"submissionId"`   // 投稿ID（UUID）
+	Topic        string  `json:"topic" dynamodbav:"text"`                  // お題の文
+	Category     *string `json:"category" dynamodbav:"category,omitempty"` // カテゴリ（任意）
+	PlayerID     string  `json:"playerId" dynamodbav:"playerId"`           // 投稿したプレイヤーID
+	PlayerName   string  `json:"playerName" dynamodbav:"playerName"`       // 投稿したプレイヤー名
+	SubmittedAt  string  `json:"submittedAt" dynamodbav:"submittedAt"`     // 投稿日時
+}
+
+// QueuedTopic - ホストに見せる出題待ちのお題
+type QueuedTopic struct {
+	Position    int     `json:"position"`    // 出題順（0が次のお題）
+	Topic       string  `json:"topic"`       // お題の文
+	Category    *string `json:"category"`    // カテゴリ（カスタムのお題・旧データはnullの場合あり）
+	Source      string  `json:"source"`      // 出典（AI/DECK/CUSTOM）
+	SubmittedBy *string `json:"submittedBy"` // お題を考えたプレイヤー名（プレイヤーの投稿のみ）
+}
+
+// TopicQueue - 出題待ちのお題と承認待ちの投稿（ホストのみ取得可能）
+type TopicQueue struct {
+	RoomID      string            `json:"roomId"`      // ルームID
+	Topics      []QueuedTopic     `json:"topics"`      // 出題待ちのお題（出題順）
+	Submissions []TopicSubmission `json:"submissions"` // 承認待ちの投稿（投稿順）
+}
+
+// RoomSettings - ホストが設定できるルームの設定
+type RoomSettings struct {
+	MaxPlayers      int      `json:"maxPlayers" dynamodbav:"maxPlayers"`           // 最大人数（0は無制限）
+	MinPlayers      int      `json:"minPlayers" dynamodbav:"minPlayers"`           // ゲーム開始に必要な最小人数（0は制限なし）
+	RequireAllReady bool     `json:"requireAllReady" dynamodbav:"requireAllReady"` // 全員の準備完了をゲーム開始の条件にするか
+	MaxRounds       int      `json:"maxRounds" dynamodbav:"maxRounds"`             // ラウンド数（0は無制限）
+	AnswerTimeLimit int      `json:"answerTimeLimit" dynamodbav:"answerTimeLimit"` // 回答制限時間（秒、0は無制限）
+	TopicCategories []string `json:"topicCategories" dynamodbav:"topicCategories"` // お題のカテゴリ（空は全カテゴリ）
+	TopicSource     string   `json:"topicSource" dynamodbav:"topicSource"`         // お題の出典（AI/DECK/MIXED）
+	TopicDeckIDs    []string `json:"topicDeckIds" dynamodbav:"topicDeckIds"`       // お題の出典に使うデッキ（DECK/MIXEDの場合）
+	CommentsEnabled bool     `json:"commentsEnabled" dynamodbav:"commentsEnabled"` // ニコニコ風コメントを生成するか
+	ScoringRule     string   `json:"scoringRule" dynamodbav:"scoringRule"`         // 得点ルール（ALL_MATCH/NONE）
+	TeamCount       int      `json:"teamCount" dynamodbav:"teamCount"`             // チーム数（0はチーム戦なし）
+	TieRule         string   `json:"tieRule" dynamodbav:"tieRule"`                 // 多数派モードで最大グループが同数の場合の扱い（ALL/NONE）
+	DiscussionTime  int      `json:"discussionTime" dynamodbav:"discussionTime"`   // ワードウルフの議論時間（秒、0は無制限）
+	WolfCount       int      `json:"wolfCount" dynamodbav:"wolfCount"`             // ワードウルフの少数派の人数
+	TopicDifficulty string   `json:"topicDifficulty" dynamodbav:"topicDifficulty"` // お題の難易度（ANY/EASY/MEDIUM/HARD/PROGRESSIVE）
+}
+
+// PublicRoomSummary - ロビーに表示する公開ルームの概要
+type PublicRoomSummary struct {
+	RoomID      string `json:"roomId"`      // ルームID
+	RoomCode    string `json:"roomCode"`    // ルームコード
+	HostName    string `json:"hostName"`    // ホスト名
+	State       string `json:"state"`       // ゲーム状態
+	PlayerCount int    `json:"playerCount"` // 参加人数
+	MaxPlayers  int    `json:"maxPlayers"`  // 最大人数（0は無制限）
+	HasPassword bool   `json:"hasPassword"` // パスワードが必要か
+	UpdatedAt   string `json:"updatedAt"`   // 最終更新日時
+}
+
+// PublicRoomConnection - 公開ルーム一覧のページ
+type PublicRoomConnection struct {
+	Items     []PublicRoomSummary `json:"items"`     // 公開ルーム
+	NextToken *string             `json:"nextToken"` // 次ページのトークン（最終ページはnull）
 }
 
 // Player - プレイヤー情報
 type Player struct {
-	PlayerID  string `json:"playerId" dynamodbav:"playerId"`   // プレイヤーID（UUID）
-	RoomID    string `json:"roomId" dynamodbav:"roomId"`       // 所属ルームID
-	RoomCode  string `json:"roomCode" dynamodbav:"-"`          // ルームコード（Subscriptionフィルタ用、DBには保存しない）
-	Name      string `json:"name" dynamodbav:"name"`           // プレイヤー名
-	Role      string `json:"role" dynamodbav:"role"`           // 役割（HOST/PLAYER）
-	Connected bool   `json:"connected" dynamodbav:"connected"` // 接続状態
-	JoinedAt  string `json:"joinedAt" dynamodbav:"joinedAt"`   // 参加日時
+	PlayerID            string `json:"playerId" dynamodbav:"playerId"`                       // プレイヤーID（UUID）
+	RoomID              string `json:"roomId" dynamodbav:"roomId"`                           // 所属ルームID
+	RoomCode            string `json:"roomCode" dynamodbav:"-"`                              // ルームコード（Subscriptionフィルタ用、DBには保存しない）
+	Name                string `json:"name" dynamodbav:"name"`                               // プレイヤー名
+	Role                string `json:"role" dynamodbav:"role"`                               // 役割（HOST/COHOST/PLAYER/SPECTATOR）
+	Connected           bool   `json:"connected" dynamodbav:"connected"`                     // 接続状態
+	Team                int    `json:"team" dynamodbav:"team"`                               // 所属チーム（1〜、0は未所属）
+	Score               int    `json:"score" dynamodbav:"score"`                             // 個人の得点（多数派モードのみ）
+	Ready               bool   `json:"ready" dynamodbav:"ready"`                             // 準備完了（ゲーム開始時にリセット）
+	WaitingForNextRound bool   `json:"waitingForNextRound" dynamodbav:"waitingForNextRound"` // ラウンド途中に参加し、次のラウンドを待っている
+	JoinedAt            string `json:"joinedAt" dynamodbav:"joinedAt"`                       // 参加日時
+	TTL                 int64  `json:"ttl" dynamodbav:"ttl"`                                 // TTL（ルームのTTLに合わせる）
+	IdentityID          string `json:"-" dynamodbav:"identityId,omitempty"`                  // Cognito Identity ID（BAN判定用、非公開）
+	DeviceToken         string `json:"-" dynamodbav:"deviceToken,omitempty"`                 // 端末トークン（BAN判定用、非公開）
+}
+
+// Ban - 追放記録（同じ端末からの再参加を拒否するために使用）
+type Ban struct {
+	BanID       string `json:"banId" dynamodbav:"banId"`             // BAN ID（UUID）
+	Name        string `json:"name" dynamodbav:"name"`               // 追放時のプレイヤー名
+	IdentityID  string `json:"-" dynamodbav:"identityId,omitempty"`  // Cognito Identity ID（非公開）
+	DeviceToken string `json:"-" dynamodbav:"deviceToken,omitempty"` // 端末トークン（非公開）
+	BannedAt    string `json:"bannedAt" dynamodbav:"bannedAt"`       // 追放日時
+}
+
+// Invite - 署名付き招待リンクの情報
+type Invite struct {
+	Token     string `json:"token"`     // 招待トークン（URLに含めて共有する）
+	RoomID    string `json:"roomId"`    // ルームID
+	ExpiresAt string `json:"expiresAt"` // 有効期限
+	MaxUses   int    `json:"maxUses"`   // 使用回数の上限（0は無制限）
 }
 
 // Answer - 回答情報
@@ -60,21 +208,120 @@ type Answer struct {
 	TextAnswer  *string `json:"textAnswer,omitempty" dynamodbav:"textAnswer,omitempty"`   // テキスト回答
 	DrawingData *string `json:"drawingData,omitempty" dynamodbav:"drawingData,omitempty"` // 絵（未使用）
 	SubmittedAt string  `json:"submittedAt" dynamodbav:"submittedAt"`                     // 提出日時
+	TTL         int64   `json:"ttl" dynamodbav:"ttl"`                                     // TTL（ルームのTTLに合わせる）
+}
+
+// TeamScore - チームの得点
+type TeamScore struct {
+	Team  int `json:"team" dynamodbav:"team"`   // チーム番号（1〜）
+	Score int `json:"score" dynamodbav:"score"` // 得点
+}
+
+// TeamVerdict - チームごとの判定結果
+type TeamVerdict struct {
+	Team    int  `json:"team" dynamodbav:"team"`       // チーム番号（1〜）
+	IsMatch bool `json:"isMatch" dynamodbav:"isMatch"` // チーム内で一致したか
+}
+
+// RoundResult - 多数派モードの判定結果（回答のグループと得点したプレイヤー）
+type RoundResult struct {
+	RoomID   string        `json:"roomId" dynamodbav:"roomId"`     // ルームID
+	Round    int           `json:"round" dynamodbav:"round"`       // ラウンド番号
+	Groups   []AnswerGroup `json:"groups" dynamodbav:"groups"`     // 回答のグループ（人数の多い順）
+	TieRule  string        `json:"tieRule" dynamodbav:"tieRule"`   // 適用した同数時のルール
+	JudgedAt string        `json:"judgedAt" dynamodbav:"judgedAt"` // 判定日時
+}
+
+// AnswerGroup - 同じ答えとみなされた回答のグループ
+type AnswerGroup struct {
+	Answer  string        `json:"answer" dynamodbav:"answer"`   // 代表の回答
+	Members []GroupMember `json:"members" dynamodbav:"members"` // グループに含まれるプレイヤー
+	Scored  bool          `json:"scored" dynamodbav:"scored"`   // このグループのメンバーが得点したか
+}
+
+// GroupMember - 回答グループのメンバー
+type GroupMember struct {
+	PlayerID   string `json:"playerId" dynamodbav:"playerId"`     // プレイヤーID
+	PlayerName string `json:"playerName" dynamodbav:"playerName"` // プレイヤー名
+	AnswerID   string `json:"answerId" dynamodbav:"answerId"`     // 回答ID
+}
+
+// WordWolfGame - ワードウルフの進行状況
+// お題・少数派・投票先は公開前に漏れないようレスポンスに含めない（自分のお題はgetMyWordで取得する）
+type WordWolfGame struct {
+	MajorityWord     string            `json:"-" dynamodbav:"majorityWord"`                              // 多数派のお題（非公開）
+	MinorityWord     string            `json:"-" dynamodbav:"minorityWord"`                              // 少数派のお題（非公開）
+	WolfIDs          []string          `json:"-" dynamodbav:"wolfIds"`                                   // 少数派のプレイヤーID（非公開）
+	Votes            map[string]string `json:"-" dynamodbav:"votes"`                                     // 投票（投票者ID→投票先ID、非公開）
+	ParticipantIDs   []string          `json:"participantIds" dynamodbav:"participantIds"`               // このゲームの参加者（観戦者を除く）
+	DiscussionEndsAt *string           `json:"discussionEndsAt" dynamodbav:"discussionEndsAt,omitempty"` // 議論の終了時刻（無制限の場合はnull）
+	VotedPlayerIDs   []string          `json:"votedPlayerIds" dynamodbav:"-"`                            // 投票済みのプレイヤーID（レスポンス用）
+	Result           *WordWolfResult   `json:"result" dynamodbav:"result,omitempty"`                     // 結果（公開後のみ）
+}
+
+// WordWolfResult - ワードウルフの結果
+type WordWolfResult struct {
+	MajorityWord string      `json:"majorityWord" dynamodbav:"majorityWord"` // 多数派のお題
+	MinorityWord string      `json:"minorityWord" dynamodbav:"minorityWord"` // 少数派のお題
+	WolfIDs      []string    `json:"wolfIds" dynamodbav:"wolfIds"`           // 少数派のプレイヤーID
+	Tally        []VoteTally `json:"tally" dynamodbav:"tally"`               // 得票数（多い順）
+	ExecutedIDs  []string    `json:"executedIds" dynamodbav:"executedIds"`   // 最多得票のプレイヤーID
+	MajorityWins bool        `json:"majorityWins" dynamodbav:"majorityWins"` // 多数派の勝利か
+	RevealedAt   string      `json:"revealedAt" dynamodbav:"revealedAt"`     // 公開日時
+}
+
+// VoteTally - プレイヤーごとの得票数
+type VoteTally struct {
+	PlayerID string `json:"playerId" dynamodbav:"playerId"` // プレイヤーID
+	Votes    int    `json:"votes" dynamodbav:"votes"`       // 得票数
+}
+
+// MyWord - プレイヤー本人にのみ返すワードウルフのお題
+type MyWord struct {
+	RoomID   string `json:"roomId"`   // ルームID
+	PlayerID string `json:"playerId"` // プレイヤーID
+	Word     string `json:"word"`     // 自分のお題
+}
+
+// TopicQuality - お題の品質（rateTopicの結果）
+type TopicQuality struct {
+	Topic           string   `json:"topic"`           // お題の文
+	RatingCount     int      `json:"ratingCount"`     // 評価の件数
+	AverageRating   *float64 `json:"averageRating"`   // 評価の平均（1〜5、評価がない場合はnull）
+	SkipCount       int      `json:"skipCount"`       // スキップされた回数
+	MatchCount      int      `json:"matchCount"`      // 一致と判定された回数
+	MissCount       int      `json:"missCount"`       // 不一致と判定された回数
+	Score           float64  `json:"score"`           // 品質スコア（0〜1）
+	LowQuality      bool     `json:"lowQuality"`      // 評価が低いため出題しないお題か
+	Difficulty      float64  `json:"difficulty"`      // 推定難易度（0〜1、判定の記録がない場合は0.5）
+	DifficultyLevel string   `json:"difficultyLevel"` // 推定難易度の区分（EASY/MEDIUM/HARD）
 }
 
 // JudgeResult - 判定結果
 type JudgeResult struct {
-	RoomID   string   `json:"roomId"`             // ルームID
-	IsMatch  bool     `json:"isMatch"`            // 一致判定結果
-	JudgedAt string   `json:"judgedAt"`           // 判定日時
-	Comments []string `json:"comments,omitempty"` // コメント
+	RoomID       string        `json:"roomId"`             // ルームID
+	IsMatch      bool          `json:"isMatch"`            // 一致判定結果
+	JudgedAt     string        `json:"judgedAt"`           // 判定日時
+	Comments     []string      `json:"comments,omitempty"` // コメント
+	TeamVerdicts []TeamVerdict `json:"teamVerdicts"`       // チームごとの判定結果（チーム戦のみ）
+	TeamScores   []TeamScore   `json:"teamScores"`         // 判定後のチームごとの得点（チーム戦のみ）
+}
+
+// AdminRoomSummary - 管理API用のルーム概要
+type AdminRoomSummary struct {
+	RoomID      string `json:"roomId"`      // ルームID
+	RoomCode    string `json:"roomCode"`    // ルームコード
+	State       string `json:"state"`       // ゲーム状態
+	PlayerCount int    `json:"playerCount"` // 参加人数
+	CreatedAt   string `json:"createdAt"`   // 作成日時
+	UpdatedAt   string `json:"updatedAt"`   // 更新日時
 }
 
-// DeleteAllDataResponse - 全データ削除レスポンス（開発用）
-type DeleteAllDataResponse struct {
-	Success       bool          `json:"success"`
-	Message       string        `json:"message"`
-	DeletedCounts DeletedCounts `json:"deletedCounts"`
+// AdminCleanupResult - 管理APIの削除結果
+type AdminCleanupResult struct {
+	DryRun        bool          `json:"dryRun"`        // trueの場合は削除せず対象のみ返す
+	RoomIDs       []string      `json:"roomIds"`       // 対象ルームID
+	DeletedCounts DeletedCounts `json:"deletedCounts"` // 削除（予定）件数
 }
 
 // DeletedCounts - 削除件数
@@ -84,16 +331,39 @@ type DeletedCounts struct {
 	Answers int `json:"answers"`
 }
 
+// OrphanCleanupReport - 孤立データ掃除の結果
+type OrphanCleanupReport struct {
+	DryRun         bool          `json:"dryRun"`         // trueの場合は削除せず対象のみ数える
+	ScannedPlayers int           `json:"scannedPlayers"` // スキャンしたプレイヤー数
+	ScannedAnswers int           `json:"scannedAnswers"` // スキャンした回答数
+	OrphanRoomIDs  []string      `json:"orphanRoomIds"`  // 既に存在しないルームのID
+	DeletedCounts  DeletedCounts `json:"deletedCounts"`  // 削除（予定）件数
+}
+
 // ===========================================
 // OpenAI API 関連の構造体
 // ===========================================
 
 // OpenAIRequest - OpenAI APIリクエスト
 type OpenAIRequest struct {
-	Model       string          `json:"model"`       // 使用モデル（gpt-4o-mini）
-	Messages    []OpenAIMessage `json:"messages"`    // メッセージ配列
-	Temperature float64         `json:"temperature"` // ランダム性（0.0-1.0）
-	MaxTokens   int             `json:"max_tokens"`  // 最大トークン数
+	Model          string                `json:"model"`                     // 使用モデル（gpt-4o-mini）
+	Messages       []OpenAIMessage       `json:"messages"`                  // メッセージ配列
+	Temperature    float64               `json:"temperature"`               // ランダム性（0.0-1.0）
+	MaxTokens      int                   `json:"max_tokens"`                // 最大トークン数
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"` // 出力形式（構造化出力の場合のみ）
+}
+
+// OpenAIResponseFormat - 構造化出力の指定
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`                  // json_schema
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"` // 出力のJSONスキーマ
+}
+
+// OpenAIJSONSchema - 構造化出力のJSONスキーマ
+type OpenAIJSONSchema struct {
+	Name   string                 `json:"name"`   // スキーマ名
+	Strict bool                   `json:"strict"` // スキーマに厳密に従わせるか
+	Schema map[string]interface{} `json:"schema"` // JSONスキーマ本体
 }
 
 // OpenAIMessage - OpenAI メッセージ
@@ -109,5 +379,19 @@ type OpenAIResponse struct {
 
 // OpenAIChoice - OpenAI 選択肢
 type OpenAIChoice struct {
-	Message OpenAIMessage `json:"message"`
+	Message      OpenAIMessage `json:"message"`
+	FinishReason string        `json:"finish_reason"` // 終了理由（stop: 完了、length: 出力の上限で打ち切り）
+}
+
+// OpenAIModerationRequest - Moderation APIリクエスト
+type OpenAIModerationRequest struct {
+	Model string `json:"model"` // 使用するモデル
+	Input string `json:"input"` // 判定する文
+}
+
+// OpenAIModerationResponse - Moderation APIレスポンス
+type OpenAIModerationResponse struct {
+	Results []struct {
+		Flagged bool `json:"flagged"` // 不適切な内容と判定されたか
+	} `json:"results"`
 }
@@ -3,17 +3,29 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strings"
-	"time"
+	"sync"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const (
+	topicsPerRequest  = 20  // お題の生成1回あたりのお題の数（出力が長いとリゾルバーの締め切りに間に合わない）
+	maxTokensPerTopic = 150 // お題1個あたりの出力トークンの上限（お題・カテゴリ・想定回答3個のJSON）
 )
 
-// generateTopics - OpenAI APIを使ってお題を130個一気に生成（高品質プロンプト）
-func generateTopics(usedTopics []string) ([]string, error) {
+// generateTopics - OpenAI APIを使ってお題を130個生成（高品質プロンプト）
+// 1回の出力が長いとリゾルバーの締め切りに間に合わないため、カテゴリの配分をtopicsPerRequest個ずつに分けて並行に生成する
+// 構造化出力（JSONスキーマ）でお題・カテゴリ・想定回答を受け取り、検証に通ったものだけを返す
+// categoriesを指定するとそのカテゴリのみから出題する
+func generateTopics(ctx context.Context, usedTopics []string, categories []string) ([]Topic, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEYが設定されていません")
@@ -25,109 +37,95 @@ func generateTopics(usedTopics []string) ([]string, error) {
 		usedTopicsMap[t] = true
 	}
 
-	// 使用済みお題のテキスト（最新100個渡す）
-	usedTopicsText := ""
-	if len(usedTopics) > 0 {
-		recentUsed := usedTopics
-		if len(usedTopics) > 100 {
-			recentUsed = usedTopics[len(usedTopics)-100:]
+	quotas := topicgen.NewQuotas(categories, topicgen.DefaultTopicCount)
+	batches := quotas.Split(topicsPerRequest)
+
+	type batchResult struct {
+		topics []topicgen.Topic
+		err    error
+	}
+	results := make([]batchResult, len(batches))
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch topicgen.Quotas) {
+			defer wg.Done()
+			topics, err := requestTopics(ctx, usedTopics, batch)
+			results[i] = batchResult{topics: topics, err: err}
+		}(i, batch)
+	}
+	wg.Wait()
+
+	// 失敗した生成があっても、他の生成のお題は使う
+	var generated []topicgen.Topic
+	var lastErr error
+	failed := 0
+	for i, r := range results {
+		if r.err != nil {
+			log.Printf("警告: お題の生成に失敗（%d/%d回目）: %v", i+1, len(batches), r.err)
+			lastErr = r.err
+			failed++
+			continue
 		}
-		usedTopicsText = fmt.Sprintf("\n\n【絶対に避けるべきお題】以下と同じ・類似のお題は絶対に出さないこと。似たパターンも禁止：\n%s", strings.Join(recentUsed, "\n"))
-	}
-
-	// 高品質なお題を生成するプロンプト
-	systemPrompt := fmt.Sprintf(`あなたは「認識合わせゲーム」のお題作成の専門家です。
-このゲームでは、参加者全員が同じ答えを思いつくことが目標です。
-
-【あなたの任務】
-日本人なら誰でも答えが一致するような、高品質なお題を130個作成してください。
-
-【高品質なお題の条件】
-1. 答えが1〜3個に自然と収束する
-2. 具体的な場面・状況で限定されている
-3. 「〜といえば？」の形式で統一
-4. 日本人の常識・共通体験に基づいている
-
-【必須のカテゴリ配分】130個の中で以下を必ず含めること：
-- 食べ物・飲み物（22問）：コンビニ、給食、お祭り、季節の食べ物、お菓子など
-- 場所・観光地（13問）：修学旅行、観光名所、都道府県の名物など
-- キャラクター・アニメ（18問）：国民的アニメ、キャラクターの特徴など
-- 学校・行事（13問）：運動会、夏休み、卒業式、授業、部活など
-- 動物・生き物（13問）：ペット、動物園、虫、水族館など
-- 色・形・特徴（13問）：「赤い〜」「丸い〜」「甘い〜」など
-- お店・チェーン（13問）：コンビニ、ファストフード、100均など
-- 乗り物・交通（8問）：電車、新幹線、飛行機など
-- スポーツ・遊び（8問）：野球、サッカー、ゲーム、カードなど
-- その他（9問）：芸能人、音楽、映画など
-
-【良いお題の例】
-- コンビニのおにぎりで一番人気の具といえば？
-- 修学旅行で行く定番の場所といえば？
-- ドラえもんの道具の定番といえば？
-- 給食の人気メニューといえば？
-- 動物園の人気者といえば？
-- 赤い野菜といえば？
-- ファストフードの定番チェーンといえば？
-- 運動会の定番競技といえば？
-
-【絶対にNGな例】
-- 「春といえば？」→ 抽象的すぎて答えが発散
-- 「好きな食べ物は？」→ 個人の好みで答えがバラバラ
-- 「有名アーティストの代表曲は？」→ 二段階で絞っていて答えが定まらない
-- 同じパターンの連続（「黄色い〜」「赤い〜」「青い〜」を連続で出すなど）%s
+		generated = append(generated, r.topics...)
+	}
+	if len(generated) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
 
-【出力形式】
-- お題のみを1行ずつ出力
-- 番号や記号は付けない
-- 答えの例や説明は絶対に含めない
-- 必ず130個出力すること`, usedTopicsText)
+	// 検証と重複チェック（ルールはcmd/topicgenと共通、並行に生成したお題同士の重複もここで除く）
+	accepted, rejected := topicgen.Accept(generated, quotas.Categories(), usedTopicsMap)
+	for _, err := range rejected {
+		log.Printf("警告: お題を除外: %v", err)
+	}
+	log.Printf("お題の生成結果: 生成=%d/%d回, 出力=%d, 採用=%d, 不正=%d", len(batches)-failed, len(batches), len(generated), len(accepted), len(rejected))
 
-	// OpenAI APIリクエストを構築（130個リクエスト）
+	if len(accepted) == 0 {
+		return nil, fmt.Errorf("有効なお題が生成されませんでした")
+	}
+	for _, warning := range topicgen.QuotaShortfalls(accepted, quotas) {
+		log.Printf("警告: %s", warning)
+	}
+
+	resultTopics := make([]Topic, 0, len(accepted))
+	for _, t := range accepted {
+		resultTopics = append(resultTopics, Topic{Text: t.Text, Category: t.Category, ExampleAnswers: t.ExampleAnswers})
+	}
+	return resultTopics, nil
+}
+
+// requestTopics - 配分に従ってお題を1回生成（検証はしない）
+func requestTopics(ctx context.Context, usedTopics []string, quotas topicgen.Quotas) ([]topicgen.Topic, error) {
 	reqBody := OpenAIRequest{
 		Model: "gpt-4o-mini",
 		Messages: []OpenAIMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: "上記の条件に従って、高品質なお題を130個生成してください。各カテゴリからバランスよく出題し、同じパターンの繰り返しを避けてください。"},
+			{Role: "system", Content: topicgen.SystemPrompt(usedTopics, quotas)},
+			{Role: "user", Content: topicgen.UserPrompt(quotas.Total())},
 		},
 		Temperature: 0.9,
-		MaxTokens:   8000,
+		MaxTokens:   topicsPerRequest * maxTokensPerTopic,
+		ResponseFormat: &OpenAIResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &OpenAIJSONSchema{
+				Name:   "topics",
+				Strict: true,
+				Schema: topicgen.ResponseSchema(quotas),
+			},
+		},
 	}
 
-	// APIを呼び出してお題を取得
-	topics, err := callOpenAI(reqBody)
+	content, err := callOpenAIContent(ctx, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
-	// お題をクリーンアップして重複チェック
-	var resultTopics []string
-	for _, topic := range topics {
-		cleaned := cleanTopic(topic)
-		if cleaned == "" {
-			continue
-		}
-
-		// 重複チェック（使用済みお題）
-		if usedTopicsMap[cleaned] {
-			continue
-		}
-
-		// 今回の結果に既にあるかチェック
-		duplicate := false
-		for _, rt := range resultTopics {
-			if rt == cleaned {
-				duplicate = true
-				break
-			}
-		}
-		if duplicate {
-			continue
-		}
-
-		resultTopics = append(resultTopics, cleaned)
+	var output struct {
+		Topics []topicgen.Topic `json:"topics"`
 	}
-
-	return resultTopics, nil
+	if err := json.Unmarshal([]byte(content), &output); err != nil {
+		return nil, fmt.Errorf("お題のJSONの解析に失敗: %w", err)
+	}
+	return output.Topics, nil
 }
 
 // cleanTopic - お題文字列をクリーンアップ
@@ -144,8 +142,90 @@ func cleanTopic(topic string) string {
 	return strings.TrimSpace(topic)
 }
 
+// requestWordPair - ワードウルフ用に、似ているが異なるお題の組（多数派・少数派）を生成
+// 候補を複数生成させ、使用済みの組（「多数派/少数派」形式）と重ならない最初の組を返す
+func requestWordPair(ctx context.Context, usedPairs []string, categories []string) (string, string, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return "", "", fmt.Errorf("OPENAI_API_KEYが設定されていません")
+	}
+
+	usedPairsMap := make(map[string]bool)
+	for _, p := range usedPairs {
+		usedPairsMap[p] = true
+	}
+
+	// 使用済みの組のテキスト（最新50組渡す）
+	usedPairsText := ""
+	if len(usedPairs) > 0 {
+		recentUsed := usedPairs
+		if len(usedPairs) > 50 {
+			recentUsed = usedPairs[len(usedPairs)-50:]
+		}
+		usedPairsText = fmt.Sprintf("\n\n【避けるべき組】以下と同じ組は出さないこと：\n%s", strings.Join(recentUsed, "\n"))
+	}
+
+	categoryText := ""
+	if len(categories) > 0 {
+		categoryText = fmt.Sprintf("\n\n【カテゴリ】以下のカテゴリのみから出題すること：%s", strings.Join(categories, "、"))
+	}
+
+	systemPrompt := fmt.Sprintf(`あなたは「ワードウルフ」のお題作成の専門家です。
+このゲームでは、多数派と少数派に似ているが異なる単語が配られ、会話から少数派を見つけ出します。
+
+【良いお題の組の条件】
+1. 2つの単語は同じジャンルで、共通点が多い
+2. ただし会話を続けると違いが見えてくる
+3. 日本人なら誰でも知っている単語
+4. 単語のみ（説明や「〜といえば？」の形式にしない）
+
+【良い例】
+うどん/そば
+海/プール
+コンビニ/スーパー
+犬/猫
+
+【出力形式】
+- 「単語/単語」の形式で1行に1組
+- 番号や記号は付けない
+- 10組出力すること%s%s`, categoryText, usedPairsText)
+
+	reqBody := OpenAIRequest{
+		Model: "gpt-4o-mini",
+		Messages: []OpenAIMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: "上記の条件に従って、ワードウルフのお題の組を10組生成してください。"},
+		},
+		Temperature: 0.9,
+		MaxTokens:   500,
+	}
+
+	lines, err := callOpenAI(ctx, reqBody)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, line := range lines {
+		majority, minority, ok := strings.Cut(cleanTopic(line), "/")
+		if !ok {
+			continue
+		}
+		majority = strings.TrimSpace(majority)
+		minority = strings.TrimSpace(minority)
+		if majority == "" || minority == "" || majority == minority {
+			continue
+		}
+		if usedPairsMap[majority+"/"+minority] || usedPairsMap[minority+"/"+majority] {
+			continue
+		}
+		return majority, minority, nil
+	}
+
+	return "", "", fmt.Errorf("お題の組を生成できませんでした")
+}
+
 // generateComments - ニコニコ動画風のコメントを生成
-func generateComments(topic string, answers []Answer) ([]string, error) {
+func generateComments(ctx context.Context, topic string, answers []Answer) ([]string, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEYが設定されていません")
@@ -198,7 +278,7 @@ func generateComments(topic string, answers []Answer) ([]string, error) {
 	}
 
 	// APIを呼び出してコメントを取得
-	comments, err := callOpenAI(reqBody)
+	comments, err := callOpenAI(ctx, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -211,59 +291,129 @@ func generateComments(topic string, answers []Answer) ([]string, error) {
 	return comments, nil
 }
 
-// callOpenAI - OpenAI APIを呼び出す共通関数
-func callOpenAI(reqBody OpenAIRequest) ([]string, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-
-	jsonData, err := json.Marshal(reqBody)
+// callOpenAI - OpenAI APIを呼び出し、出力を行ごとのリストで返す共通関数
+func callOpenAI(ctx context.Context, reqBody OpenAIRequest) ([]string, error) {
+	content, err := callOpenAIContent(ctx, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("リクエストのマーシャルに失敗: %w", err)
+		return nil, err
 	}
 
-	// HTTPリクエストを作成
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("リクエストの作成に失敗: %w", err)
+	// 改行で分割してリストに変換
+	lines := strings.Split(content, "\n")
+
+	var results []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			results = append(results, line)
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	return results, nil
+}
 
-	// APIを呼び出し（90個生成には時間がかかるため60秒に設定）
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
+// callOpenAIContent - OpenAI APIを呼び出し、出力をそのまま返す（構造化出力はこちらを使う）
+// 429・5xx・通信エラーはバックオフしながら再試行し、呼び出し元（リゾルバー）の締め切りを超えない（breaker.go）
+func callOpenAIContent(ctx context.Context, reqBody OpenAIRequest) (string, error) {
+	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("OpenAI APIの呼び出しに失敗: %w", err)
+		return "", fmt.Errorf("リクエストのマーシャルに失敗: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// エラーレスポンスをチェック
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI APIエラー: %d - %s", resp.StatusCode, string(body))
+	structured := reqBody.ResponseFormat != nil
+	return withLLMRetry(ctx, func(ctx context.Context) (string, error) {
+		return postChatCompletion(ctx, jsonData, structured)
+	})
+}
+
+// postChatCompletion - Chat Completions APIを1回呼び出す
+// 出力が上限で打ち切られた場合、構造化出力はJSONが不完全なためerrOutputTruncatedを返し、行ごとの出力は得られた分を返す
+func postChatCompletion(ctx context.Context, jsonData []byte, structured bool) (string, error) {
+	body, err := postOpenAI(ctx, "chat/completions", jsonData)
+	if err != nil {
+		return "", err
 	}
 
 	// レスポンスをパース
 	var openaiResp OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return nil, fmt.Errorf("レスポンスのデコードに失敗: %w", err)
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		return "", fmt.Errorf("レスポンスのデコードに失敗: %w", err)
 	}
 
 	if len(openaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("レスポンスに選択肢がありません")
+		return "", fmt.Errorf("レスポンスに選択肢がありません")
+	}
+	if openaiResp.Choices[0].FinishReason == "length" {
+		if structured {
+			return "", errOutputTruncated
+		}
+		log.Println("警告: OpenAI APIの出力が上限のトークン数で打ち切られたため、得られた分のみ使用します")
 	}
 
-	// 改行で分割してリストに変換
-	generatedText := strings.TrimSpace(openaiResp.Choices[0].Message.Content)
-	lines := strings.Split(generatedText, "\n")
+	return strings.TrimSpace(openaiResp.Choices[0].Message.Content), nil
+}
 
-	var results []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			results = append(results, line)
+// moderateText - OpenAIのModeration APIで不適切な内容か判定（再試行・ブレーカーはお題の生成と共通）
+func moderateText(ctx context.Context, text string) (bool, error) {
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		return false, fmt.Errorf("OPENAI_API_KEYが設定されていません")
+	}
+	jsonData, err := json.Marshal(OpenAIModerationRequest{Model: "omni-moderation-latest", Input: text})
+	if err != nil {
+		return false, fmt.Errorf("リクエストのマーシャルに失敗: %w", err)
+	}
+
+	body, err := withLLMRetry(ctx, func(ctx context.Context) (string, error) {
+		body, err := postOpenAI(ctx, "moderations", jsonData)
+		return string(body), err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var resp OpenAIModerationResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return false, fmt.Errorf("レスポンスのデコードに失敗: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return false, fmt.Errorf("レスポンスに判定結果がありません")
+	}
+	return resp.Results[0].Flagged, nil
+}
+
+// postOpenAI - OpenAI APIのエンドポイントを1回呼び出し、レスポンスの本文を返す
+func postOpenAI(ctx context.Context, endpoint string, jsonData []byte) ([]byte, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+
+	// HTTPリクエストを作成（タイムアウトはコンテキストの締め切りに従う）
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/"+endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("OpenAI APIの呼び出しに失敗: %w", err)
+		// 締め切り・キャンセルによる失敗は再試行しない
+		if ctx.Err() != nil {
+			return nil, err
 		}
+		return nil, &retryableError{err: err}
 	}
+	defer resp.Body.Close()
 
-	return results, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("レスポンスの読み込みに失敗: %w", err)}
+	}
+
+	// エラーレスポンスをチェック
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, body)
+	}
+
+	return body, nil
 }
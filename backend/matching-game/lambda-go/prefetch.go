@@ -0,0 +1,155 @@
+// prefetch.go - ルームのお題プールの先読み補充
+// プールの残りが少なくなった時点で非同期に補充を始め、プールが空になってから同期で補充するのを避ける
+// Lambda上では自分自身を非同期呼び出し（InvocationType=Event）し、ローカル実行では同じプロセスのゴルーチンで補充する
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	lambdasvc "github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const (
+	topicPrefetchWatermark = 10               // お題プールの残りがこの数以下になったら補充を始める
+	localPrefetchTimeout   = 30 * time.Second // ローカル実行での補充のタイムアウト（Lambdaのタイムアウトに合わせる）
+)
+
+// topicPrefetcher - お題プールの補充を非同期に依頼する
+type topicPrefetcher interface {
+	enqueue(ctx context.Context, roomID string) error
+}
+
+// prefetcher - 実行環境に応じた補充の依頼先（init時に設定）
+var prefetcher topicPrefetcher
+
+// newTopicPrefetcher - Lambda上では自己呼び出し、それ以外ではゴルーチンで補充する
+func newTopicPrefetcher(cfg aws.Config) topicPrefetcher {
+	if name := os.Getenv("AWS_LAMBDA_FUNCTION_NAME"); name != "" {
+		return &lambdaPrefetcher{client: lambdasvc.NewFromConfig(cfg), functionName: name}
+	}
+	return localPrefetcher{}
+}
+
+// lambdaPrefetcher - 自分自身を非同期に呼び出して補充する（呼び出し元のレスポンスは待たせない）
+type lambdaPrefetcher struct {
+	client       *lambdasvc.Client
+	functionName string
+}
+
+func (p *lambdaPrefetcher) enqueue(ctx context.Context, roomID string) error {
+	payload, err := json.Marshal(AppSyncEvent{
+		Info:      AppSyncInfo{FieldName: "prefetchTopics"},
+		Arguments: map[string]interface{}{"roomId": roomID},
+	})
+	if err != nil {
+		return fmt.Errorf("補充依頼のマーシャルに失敗: %w", err)
+	}
+	_, err = p.client.Invoke(ctx, &lambdasvc.InvokeInput{
+		FunctionName:   aws.String(p.functionName),
+		InvocationType: lambdatypes.InvocationTypeEvent,
+		Payload:        payload,
+	})
+	if err != nil {
+		return fmt.Errorf("補充の非同期呼び出しに失敗: %w", err)
+	}
+	return nil
+}
+
+// localPrefetcher - 同じプロセスのゴルーチンで補充する（ローカル実行用）
+type localPrefetcher struct{}
+
+func (localPrefetcher) enqueue(_ context.Context, roomID string) error {
+	go func() {
+		// 呼び出し元のリクエストが終わっても続けるため、独立したコンテキストを使う
+		ctx, cancel := context.WithTimeout(context.Background(), localPrefetchTimeout)
+		defer cancel()
+		if _, err := prefetchTopics(ctx, map[string]interface{}{"roomId": roomID}); err != nil {
+			log.Printf("警告: お題プールの補充に失敗 %s: %v", roomID, err)
+		}
+	}()
+	return nil
+}
+
+// maybePrefetchTopics - お題プールの残りが少なければ補充を依頼（失敗しても進行は止めない）
+func maybePrefetchTopics(ctx context.Context, roomID string, remaining int) {
+	if remaining > topicPrefetchWatermark {
+		return
+	}
+	log.Printf("お題プールの残りが%d個のため補充を依頼: roomId=%s", remaining, roomID)
+	if err := prefetcher.enqueue(ctx, roomID); err != nil {
+		log.Printf("警告: %v", err)
+	}
+}
+
+// prefetchTopics - お題バンクからお題を取り出してルームのお題プールの末尾に追加
+// AppSyncスキーマには存在しないフィールドのため、Lambdaの直接呼び出し（またはローカルのゴルーチン）でのみ実行される
+// 補充中にnextRound等が先頭のお題を取り出してもその結果を上書きしないよう、プールの置き換えではなく末尾への追加で更新し、
+// 重複して補充しないよう、プールの残りがまだ少ない場合のみ追加する
+// （追加の直後にnextRound等が古いプールで上書きした場合は追加分が失われるが、残りが少ないままなので次のラウンドで再度補充される）
+func prefetchTopics(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	log.Printf("お題プールの補充: roomId=%s", roomID)
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil || room.State == "CLOSED" {
+		log.Printf("ルームが存在しないかクローズ済みのため補充しません: roomId=%s", roomID)
+		return nil, nil
+	}
+	if len(room.TopicsPool) > topicPrefetchWatermark {
+		log.Printf("お題プールは補充済みです: roomId=%s, 残り%d個", roomID, len(room.TopicsPool))
+		return room, nil
+	}
+
+	// プールに残っているお題も取り出し対象から除外する
+	exclude := append(append([]string{}, room.UsedTopics...), topicTexts(room.TopicsPool)...)
+	newTopics, err := drawTopics(ctx, room, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("お題の取得に失敗: %w", err)
+	}
+	topics, err := marshalTopicList(newTopics)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{
+		"#topicsPool": "topicsPool",
+	}
+	values := map[string]types.AttributeValue{
+		":topics":    topics,
+		":watermark": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", topicPrefetchWatermark)},
+	}
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+		UpdateExpression:          aws.String("SET #topicsPool = list_append(#topicsPool, :topics)" + queueVersionUpdate(names, values)),
+		ConditionExpression:       aws.String("size(#topicsPool) <= :watermark"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			log.Printf("別の補充が先に完了したため追加しません: roomId=%s", roomID)
+			return room, nil
+		}
+		return nil, fmt.Errorf("お題プールの更新に失敗: %w", err)
+	}
+
+	log.Printf("お題プールに%d個追加しました: roomId=%s", len(newTopics), roomID)
+	return room, nil
+}
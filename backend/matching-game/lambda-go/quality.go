@@ -0,0 +1,285 @@
+// quality.go - お題の品質の記録（プレイヤーの評価・スキップ・判定結果）と評価の低いお題の除外
+// 記録はお題の文ごとに全ルームで集計し、バンク・その場の生成・組み込みのお題のいずれからも評価の低いお題を出題しない
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	minTopicRating = 1 // 評価の最小値
+	maxTopicRating = 5 // 評価の最大値
+
+	// 品質スコアは、評価（1〜5を0〜1に換算）・スキップ（0）・一致（1）・不一致（missSignal）の平均を、
+	// 事前値qualityPriorをqualityPriorWeight件分加えて平滑化したもの（記録が少ないお題はqualityPriorに近い値になる）
+	qualityPrior       = 0.6
+	qualityPriorWeight = 5.0
+	missSignal         = 0.3 // 不一致は答えが割れただけの場合もあるため、スキップほど低くは扱わない
+
+	lowQualityThreshold  = 0.35 // 品質スコアがこの値未満のお題は出題しない
+	lowQualityMinSignals = 5    // 除外を判断するのに必要な記録の件数
+
+	topicStatsCacheDuration = 5 * time.Minute // お題の記録をLambdaのメモリに保持する時間
+)
+
+// topicStatsItem - お題ごとの品質の記録（DynamoDBの項目）
+type topicStatsItem struct {
+	Text        string `dynamodbav:"text"`        // お題の文
+	RatingSum   int    `dynamodbav:"ratingSum"`   // 評価の合計
+	RatingCount int    `dynamodbav:"ratingCount"` // 評価の件数
+	SkipCount   int    `dynamodbav:"skipCount"`   // スキップされた回数
+	MatchCount  int    `dynamodbav:"matchCount"`  // 一致と判定された回数
+	MissCount   int    `dynamodbav:"missCount"`   // 不一致と判定された回数
+
+	JudgedRounds   int `dynamodbav:"judgedRounds"`   // 回答のグループ数・人数を記録したラウンド数（難易度の推定用）
+	GroupCountSum  int `dynamodbav:"groupCountSum"`  // 各ラウンドの回答のグループ数の合計
+	PlayerCountSum int `dynamodbav:"playerCountSum"` // 各ラウンドの回答者数の合計
+}
+
+// topicStatsCache - お題ごとの記録のキャッシュ（ウォームスタート間で共有）
+var topicStatsCache struct {
+	mu      sync.Mutex
+	entries map[string]topicStatsEntry
+}
+
+// topicStatsEntry - 1お題分の記録のキャッシュ（statsがnilの場合は記録なし）
+type topicStatsEntry struct {
+	stats    *topicStatsItem
+	loadedAt time.Time
+}
+
+// rateTopic - プレイヤーがお題を評価（1〜5、同じお題は1プレイヤー1回まで）
+// 評価できるのは参加しているルームの現在のお題か使用済みのお題のみ
+func rateTopic(ctx context.Context, args map[string]interface{}) (*TopicQuality, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	topic := args["topic"].(string)
+	rating := 0
+	if v, ok := args["rating"].(float64); ok {
+		rating = int(v)
+	}
+
+	log.Printf("お題の評価: roomId=%s, playerId=%s, topic=%s, rating=%d", roomID, playerID, topic, rating)
+
+	if rating < minTopicRating || rating > maxTopicRating {
+		return nil, fmt.Errorf("評価は%d〜%dで指定してください", minTopicRating, maxTopicRating)
+	}
+
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return nil, err
+	}
+	player, err := getPlayerItem(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.RoomID != roomID {
+		return nil, fmt.Errorf("このルームのプレイヤーではありません")
+	}
+	if (room.Topic == nil || *room.Topic != topic) && !slices.Contains(room.UsedTopics, topic) {
+		return nil, fmt.Errorf("このルームで出題されていないお題は評価できません")
+	}
+
+	result, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(topicStatsTable),
+		Key: map[string]types.AttributeValue{
+			"text": &types.AttributeValueMemberS{Value: topic},
+		},
+		UpdateExpression:    aws.String("ADD #ratingSum :rating, #ratingCount :one, #raters :rater SET #updatedAt = :updatedAt"),
+		ConditionExpression: aws.String("attribute_not_exists(#raters) OR NOT contains(#raters, :playerId)"),
+		ExpressionAttributeNames: map[string]string{
+			"#ratingSum":   "ratingSum",
+			"#ratingCount": "ratingCount",
+			"#raters":      "raters",
+			"#updatedAt":   "updatedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":rating":    &types.AttributeValueMemberN{Value: strconv.Itoa(rating)},
+			":one":       &types.AttributeValueMemberN{Value: "1"},
+			":rater":     &types.AttributeValueMemberSS{Value: []string{playerID}},
+			":playerId":  &types.AttributeValueMemberS{Value: playerID},
+			":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil, fmt.Errorf("このお題は既に評価済みです")
+		}
+		return nil, fmt.Errorf("お題の評価の保存に失敗: %w", err)
+	}
+
+	var stats topicStatsItem
+	if err := attributevalue.UnmarshalMap(result.Attributes, &stats); err != nil {
+		return nil, fmt.Errorf("お題の評価のアンマーシャルに失敗: %w", err)
+	}
+	return stats.quality(), nil
+}
+
+// recordTopicSkip - お題がスキップされたことを記録（失敗しても進行は止めない）
+func recordTopicSkip(ctx context.Context, topic string) {
+	addTopicStats(ctx, topic, map[string]int{"skipCount": 1})
+}
+
+// addTopicStats - お題の記録の各件数に加算（増減のない件数は更新しない）
+func addTopicStats(ctx context.Context, topic string, deltas map[string]int) {
+	names := map[string]string{"#updatedAt": "updatedAt"}
+	values := map[string]types.AttributeValue{
+		":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+	}
+	expr := ""
+	for attr, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		if expr != "" {
+			expr += ", "
+		}
+		names["#"+attr] = attr
+		values[":"+attr] = &types.AttributeValueMemberN{Value: strconv.Itoa(delta)}
+		expr += "#" + attr + " :" + attr
+	}
+	if expr == "" {
+		return
+	}
+
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(topicStatsTable),
+		Key: map[string]types.AttributeValue{
+			"text": &types.AttributeValueMemberS{Value: topic},
+		},
+		UpdateExpression:          aws.String("ADD " + expr + " SET #updatedAt = :updatedAt"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		log.Printf("警告: お題の記録の更新に失敗 %s: %v", topic, err)
+	}
+}
+
+// quality - 記録から品質スコアを計算
+func (s topicStatsItem) quality() *TopicQuality {
+	signals := float64(s.RatingCount + s.SkipCount + s.MatchCount + s.MissCount)
+	sum := float64(s.RatingSum-s.RatingCount*minTopicRating)/float64(maxTopicRating-minTopicRating) +
+		float64(s.MatchCount) + float64(s.MissCount)*missSignal
+
+	q := &TopicQuality{
+		Topic:       s.Text,
+		RatingCount: s.RatingCount,
+		SkipCount:   s.SkipCount,
+		MatchCount:  s.MatchCount,
+		MissCount:   s.MissCount,
+		Score:       (sum + qualityPrior*qualityPriorWeight) / (signals + qualityPriorWeight),
+	}
+	if s.RatingCount > 0 {
+		avg := float64(s.RatingSum) / float64(s.RatingCount)
+		q.AverageRating = &avg
+	}
+	q.LowQuality = int(signals) >= lowQualityMinSignals && q.Score < lowQualityThreshold
+	q.Difficulty = s.difficulty(answersPrior(0))
+	q.DifficultyLevel = difficultyLevel(q.Difficulty)
+	return q
+}
+
+// loadTopicStats - 指定したお題の記録（キャッシュが有効な間はDynamoDBを読まない）
+// 出題の候補のお題だけをBatchGetItemで読み、読めない場合は記録なしとして扱う（評価による除外・難易度の推定をせずに出題を続ける）
+func loadTopicStats(ctx context.Context, texts []string) map[string]topicStatsItem {
+	topicStatsCache.mu.Lock()
+	defer topicStatsCache.mu.Unlock()
+	if topicStatsCache.entries == nil {
+		topicStatsCache.entries = make(map[string]topicStatsEntry)
+	}
+
+	stats := make(map[string]topicStatsItem)
+	var missing []string
+	var keys []map[string]types.AttributeValue
+	requested := make(map[string]bool)
+	for _, text := range texts {
+		if requested[text] {
+			continue
+		}
+		requested[text] = true
+		if e, ok := topicStatsCache.entries[text]; ok && time.Since(e.loadedAt) < topicStatsCacheDuration {
+			if e.stats != nil {
+				stats[text] = *e.stats
+			}
+			continue
+		}
+		missing = append(missing, text)
+		keys = append(keys, map[string]types.AttributeValue{
+			"text": &types.AttributeValueMemberS{Value: text},
+		})
+	}
+	if len(keys) == 0 {
+		return stats
+	}
+
+	items, err := batchGetItems(ctx, topicStatsTable, keys, types.KeysAndAttributes{})
+	if err != nil {
+		log.Printf("警告: お題の記録の取得に失敗: %v", err)
+		return stats
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		var s topicStatsItem
+		if err := attributevalue.UnmarshalMap(item, &s); err != nil {
+			log.Printf("警告: お題の記録のアンマーシャルに失敗: %v", err)
+			continue
+		}
+		stats[s.Text] = s
+		topicStatsCache.entries[s.Text] = topicStatsEntry{stats: &s, loadedAt: now}
+	}
+	// 記録のないお題も、次の読み込みで問い合わせ直さないようキャッシュする
+	for _, text := range missing {
+		if _, ok := stats[text]; !ok {
+			topicStatsCache.entries[text] = topicStatsEntry{loadedAt: now}
+		}
+	}
+	return stats
+}
+
+// loadLowQualityTopics - 指定したお題のうち出題しないお題（品質スコアが低いお題）の集合
+func loadLowQualityTopics(ctx context.Context, texts []string) map[string]bool {
+	topics := make(map[string]bool)
+	for text, s := range loadTopicStats(ctx, texts) {
+		if s.quality().LowQuality {
+			topics[text] = true
+		}
+	}
+	return topics
+}
+
+// withoutLowQuality - 評価の低いお題を除いたお題のリスト（ホストが位置を指定したお題は残す）
+func withoutLowQuality(ctx context.Context, topics []Topic) []Topic {
+	low := loadLowQualityTopics(ctx, topicTexts(topics))
+	if len(low) == 0 {
+		return topics
+	}
+	filtered := []Topic{}
+	for _, t := range topics {
+		if !low[t.Text] || t.Pinned {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
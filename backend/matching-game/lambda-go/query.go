@@ -3,7 +3,10 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -11,6 +14,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// 公開ルーム一覧の1ページあたりの件数
+const (
+	defaultPublicRoomsLimit = 20
+	maxPublicRoomsLimit     = 50
+)
+
 // getRoom - ルーム情報を取得
 // プレイヤーと回答も結合して返す
 func getRoom(ctx context.Context, args map[string]interface{}) (*Room, error) {
@@ -36,16 +45,7 @@ func getRoom(ctx context.Context, args map[string]interface{}) (*Room, error) {
 		return nil, fmt.Errorf("ルームのアンマーシャルに失敗: %w", err)
 	}
 
-	// nullの場合は空配列を設定（GraphQLスキーマでnon-nullableのため）
-	if room.TopicsPool == nil {
-		room.TopicsPool = []string{}
-	}
-	if room.UsedTopics == nil {
-		room.UsedTopics = []string{}
-	}
-	if room.Comments == nil {
-		room.Comments = []string{}
-	}
+	normalizeRoom(&room)
 
 	// プレイヤー一覧を取得して結合
 	players, err := listPlayers(ctx, map[string]interface{}{"roomId": roomID})
@@ -68,6 +68,111 @@ func getRoom(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	return &room, nil
 }
 
+// getRoomItem - ルーム単体を取得（プレイヤー・回答は結合しない）
+// 状態やTTLの確認だけが必要な場合に使用する
+func getRoomItem(ctx context.Context, roomID string) (*Room, error) {
+	result, err := ddbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ルームの取得に失敗: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var room Room
+	if err := attributevalue.UnmarshalMap(result.Item, &room); err != nil {
+		return nil, fmt.Errorf("ルームのアンマーシャルに失敗: %w", err)
+	}
+	normalizeRoom(&room)
+
+	return &room, nil
+}
+
+// getPlayerItem - プレイヤー単体を取得（存在しない場合はnil）
+func getPlayerItem(ctx context.Context, playerID string) (*Player, error) {
+	result, err := ddbClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(playerTable),
+		Key: map[string]types.AttributeValue{
+			"playerId": &types.AttributeValueMemberS{Value: playerID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("プレイヤーの取得に失敗: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var player Player
+	if err := attributevalue.UnmarshalMap(result.Item, &player); err != nil {
+		return nil, fmt.Errorf("プレイヤーのアンマーシャルに失敗: %w", err)
+	}
+
+	return &player, nil
+}
+
+// normalizeRoom - DBから読み込んだルームの欠損値を補完
+func normalizeRoom(room *Room) {
+	// nullの場合は空配列を設定（GraphQLスキーマでnon-nullableのため）
+	if room.TopicsPool == nil {
+		room.TopicsPool = []Topic{}
+	}
+	room.TopicsPoolTexts = topicTexts(room.TopicsPool)
+	if room.UsedTopics == nil {
+		room.UsedTopics = []string{}
+	}
+	if room.Comments == nil {
+		room.Comments = []string{}
+	}
+	if room.BanList == nil {
+		room.BanList = []Ban{}
+	}
+	if room.TeamScores == nil {
+		room.TeamScores = []TeamScore{}
+	}
+	if room.LastTeamVerdicts == nil {
+		room.LastTeamVerdicts = []TeamVerdict{}
+	}
+	if room.Visibility == "" {
+		room.Visibility = defaultVisibility
+	}
+	room.HasPassword = room.PasswordHash != ""
+	// 設定導入前に作成されたルームは既定値で補完
+	if room.Settings == nil {
+		settings := defaultRoomSettings()
+		room.Settings = &settings
+	}
+	if room.Settings.TopicCategories == nil {
+		room.Settings.TopicCategories = []string{}
+	}
+	if room.Settings.TopicDeckIDs == nil {
+		room.Settings.TopicDeckIDs = []string{}
+	}
+	if room.Settings.TieRule == "" {
+		room.Settings.TieRule = defaultTieRule
+	}
+	if room.Settings.WolfCount == 0 {
+		room.Settings.WolfCount = defaultWolfCount
+	}
+	if room.Settings.TopicDifficulty == "" {
+		room.Settings.TopicDifficulty = defaultTopicDifficulty
+	}
+	// ゲームの種類導入前に作成されたルームは認識合わせ
+	if room.GameType == "" {
+		room.GameType = defaultGameType
+	}
+	if room.WordWolf != nil {
+		normalizeWordWolf(room.WordWolf)
+	}
+}
+
 // getRoomByCode - ルームコードからルームを検索
 func getRoomByCode(ctx context.Context, args map[string]interface{}) (*Room, error) {
 	roomCode := args["roomCode"].(string)
@@ -94,16 +199,7 @@ func getRoomByCode(ctx context.Context, args map[string]interface{}) (*Room, err
 		return nil, fmt.Errorf("ルームのアンマーシャルに失敗: %w", err)
 	}
 
-	// nullの場合は空配列を設定（GraphQLスキーマでnon-nullableのため）
-	if room.TopicsPool == nil {
-		room.TopicsPool = []string{}
-	}
-	if room.UsedTopics == nil {
-		room.UsedTopics = []string{}
-	}
-	if room.Comments == nil {
-		room.Comments = []string{}
-	}
+	normalizeRoom(&room)
 
 	// プレイヤーと回答を結合
 	players, err := listPlayers(ctx, map[string]interface{}{"roomId": room.RoomID})
@@ -125,6 +221,140 @@ func getRoomByCode(ctx context.Context, args map[string]interface{}) (*Room, err
 	return &room, nil
 }
 
+// listPublicRooms - ロビー用に公開ルームを最終更新の新しい順に取得
+// nextTokenはDynamoDBのLastEvaluatedKeyをBase64エンコードしたもの
+// クローズ済みのルームはフィルタで除くため、limit個集まるかデータが尽きるまでクエリを続ける
+func listPublicRooms(ctx context.Context, args map[string]interface{}) (*PublicRoomConnection, error) {
+	limit := defaultPublicRoomsLimit
+	if v, ok := args["limit"].(float64); ok && v > 0 {
+		limit = int(v)
+	}
+	if limit > maxPublicRoomsLimit {
+		limit = maxPublicRoomsLimit
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(roomTable),
+		IndexName:              aws.String("visibility-updatedAt-index"),
+		KeyConditionExpression: aws.String("#visibility = :visibility"),
+		FilterExpression:       aws.String("#state <> :closed"),
+		ExpressionAttributeNames: map[string]string{
+			"#visibility": "visibility",
+			"#state":      "state",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":visibility": &types.AttributeValueMemberS{Value: "PUBLIC"},
+			":closed":     &types.AttributeValueMemberS{Value: "CLOSED"},
+		},
+		ScanIndexForward: aws.Bool(false), // 最近更新されたルームから
+	}
+
+	if token, ok := args["nextToken"].(string); ok && token != "" {
+		startKey, err := decodePageToken(token)
+		if err != nil {
+			return nil, err
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Limitはフィルタ前の件数のため、残りの件数だけ読むことで、limitを超えて読み飛ばすルームが出ないようにする
+	var rooms []Room
+	var lastKey map[string]types.AttributeValue
+	for {
+		input.Limit = aws.Int32(int32(limit - len(rooms)))
+		result, err := ddbClient.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("公開ルームの検索に失敗: %w", err)
+		}
+
+		var page []Room
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("ルームのアンマーシャルに失敗: %w", err)
+		}
+		rooms = append(rooms, page...)
+
+		lastKey = result.LastEvaluatedKey
+		if len(lastKey) == 0 || len(rooms) >= limit {
+			break
+		}
+		input.ExclusiveStartKey = lastKey
+	}
+
+	roomIDs := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.RoomID)
+	}
+	playersByRoom, err := listPlayersByRoom(ctx, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &PublicRoomConnection{Items: []PublicRoomSummary{}}
+	for i := range rooms {
+		room := &rooms[i]
+		normalizeRoom(room)
+
+		players := playersByRoom[room.RoomID]
+		hostName := ""
+		for _, p := range players {
+			if p.PlayerID == room.HostID {
+				hostName = p.Name
+			}
+		}
+
+		connection.Items = append(connection.Items, PublicRoomSummary{
+			RoomID:      room.RoomID,
+			RoomCode:    room.RoomCode,
+			HostName:    hostName,
+			State:       room.State,
+			PlayerCount: countActivePlayers(players),
+			MaxPlayers:  room.Settings.MaxPlayers,
+			HasPassword: room.HasPassword,
+			UpdatedAt:   room.UpdatedAt,
+		})
+	}
+
+	if len(lastKey) > 0 {
+		token, err := encodePageToken(lastKey)
+		if err != nil {
+			return nil, err
+		}
+		connection.NextToken = &token
+	}
+
+	return connection, nil
+}
+
+// encodePageToken - LastEvaluatedKeyをクライアントに渡せる文字列に変換
+func encodePageToken(key map[string]types.AttributeValue) (string, error) {
+	var plain map[string]string
+	if err := attributevalue.UnmarshalMap(key, &plain); err != nil {
+		return "", fmt.Errorf("ページトークンの作成に失敗: %w", err)
+	}
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return "", fmt.Errorf("ページトークンの作成に失敗: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// decodePageToken - ページトークンをExclusiveStartKeyに戻す
+func decodePageToken(token string) (map[string]types.AttributeValue, error) {
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("nextTokenが不正です")
+	}
+	var plain map[string]string
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, fmt.Errorf("nextTokenが不正です")
+	}
+	key, err := attributevalue.MarshalMap(plain)
+	if err != nil {
+		return nil, fmt.Errorf("nextTokenが不正です")
+	}
+	return key, nil
+}
+
 // listPlayers - ルームのプレイヤー一覧を取得
 func listPlayers(ctx context.Context, args map[string]interface{}) ([]Player, error) {
 	roomID := args["roomId"].(string)
@@ -150,6 +380,30 @@ func listPlayers(ctx context.Context, args map[string]interface{}) ([]Player, er
 	return players, nil
 }
 
+// listPlayersByRoom - 複数のルームのプレイヤー一覧をまとめて取得（ルームごとのクエリを並行に行う）
+func listPlayersByRoom(ctx context.Context, roomIDs []string) (map[string][]Player, error) {
+	players := make([][]Player, len(roomIDs))
+	errs := make([]error, len(roomIDs))
+	var wg sync.WaitGroup
+	for i, roomID := range roomIDs {
+		wg.Add(1)
+		go func(i int, roomID string) {
+			defer wg.Done()
+			players[i], errs[i] = listPlayers(ctx, map[string]interface{}{"roomId": roomID})
+		}(i, roomID)
+	}
+	wg.Wait()
+
+	byRoom := make(map[string][]Player, len(roomIDs))
+	for i, roomID := range roomIDs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		byRoom[roomID] = players[i]
+	}
+	return byRoom, nil
+}
+
 // listAnswers - ルームの回答一覧を取得
 func listAnswers(ctx context.Context, args map[string]interface{}) ([]Answer, error) {
 	roomID := args["roomId"].(string)
@@ -0,0 +1,436 @@
+// queue.go - ホストによるお題キュー（お題プール）の確認・並べ替え・削除とカスタムのお題の追加
+// カスタムのお題はホストが入力するか、プレイヤーの投稿をホストが承認して追加する（いずれも長さの検証とモデレーションを通す）
+// キューの更新はプール全体の置き換えのため、読み込み後にnextRound・補充等でプールが変わっていた場合（queueVersionが進んでいた場合）は保存しない
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const (
+	customTopicSource = "CUSTOM" // ホストが追加したお題の出典
+
+	maxPendingSubmissionsPerPlayer = 3  // 1プレイヤーが承認待ちにできる投稿の数
+	maxTopicSubmissions            = 20 // ルーム全体の承認待ちの投稿の上限
+)
+
+// getTopicQueue - 出題待ちのお題と承認待ちの投稿を取得（ホストのみ）
+func getTopicQueue(ctx context.Context, args map[string]interface{}) (*TopicQueue, error) {
+	room, err := loadQueueRoom(ctx, args, true)
+	if err != nil {
+		return nil, err
+	}
+	return topicQueueOf(room.RoomID, room.TopicsPool, room.TopicSubmissions), nil
+}
+
+// addCustomTopic - カスタムのお題をキューに追加（ホストのみ）
+// submissionIdを指定した場合はプレイヤーの投稿を承認して追加し、topicを指定した場合はホストが入力したお題を検証して追加する
+// positionは追加する位置（省略時は0=次のお題、キューの長さを超える場合は末尾）
+func addCustomTopic(ctx context.Context, args map[string]interface{}) (*TopicQueue, error) {
+	room, err := loadQueueRoom(ctx, args, true)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions := room.TopicSubmissions
+	var topic Topic
+	if submissionID, ok := args["submissionId"].(string); ok && submissionID != "" {
+		index := findSubmission(submissions, submissionID)
+		if index < 0 {
+			return nil, fmt.Errorf("投稿が見つかりません")
+		}
+		s := submissions[index]
+		// 投稿時に検証・モデレーション済みのため、重複のみ確認する
+		if err := checkTopicNotQueued(room, s.Topic); err != nil {
+			return nil, err
+		}
+		topic = customTopic(s.Topic, s.Category)
+		topic.SubmittedBy = s.PlayerName
+		submissions = append(append([]TopicSubmission{}, submissions[:index]...), submissions[index+1:]...)
+	} else {
+		text, _ := args["topic"].(string)
+		category, _ := args["category"].(string)
+		t, err := validateCustomTopic(ctx, room, text, category)
+		if err != nil {
+			return nil, err
+		}
+		topic = t
+	}
+
+	position := 0
+	if v, ok := args["position"].(float64); ok {
+		position = int(v)
+	}
+	if position < 0 {
+		return nil, fmt.Errorf("位置は0以上で指定してください")
+	}
+	if position > len(room.TopicsPool) {
+		position = len(room.TopicsPool)
+	}
+
+	pool := make([]Topic, 0, len(room.TopicsPool)+1)
+	pool = append(pool, room.TopicsPool[:position]...)
+	pool = append(pool, topic)
+	pool = append(pool, room.TopicsPool[position:]...)
+
+	if err := saveTopicQueue(ctx, room, pool, submissions); err != nil {
+		return nil, err
+	}
+	log.Printf("カスタムのお題を追加しました: roomId=%s, position=%d, topic=%s", room.RoomID, position, topic.Text)
+	return topicQueueOf(room.RoomID, pool, submissions), nil
+}
+
+// removeQueuedTopic - キューからお題を削除（ホストのみ）
+// 取得後にキューが変わっていた場合に別のお題を消さないよう、位置とお題の文の両方を指定する
+func removeQueuedTopic(ctx context.Context, args map[string]interface{}) (*TopicQueue, error) {
+	room, err := loadQueueRoom(ctx, args, true)
+	if err != nil {
+		return nil, err
+	}
+	position, err := queuedTopicPosition(room.TopicsPool, args, "position")
+	if err != nil {
+		return nil, err
+	}
+
+	pool := append(append([]Topic{}, room.TopicsPool[:position]...), room.TopicsPool[position+1:]...)
+	if err := saveTopicQueue(ctx, room, pool, room.TopicSubmissions); err != nil {
+		return nil, err
+	}
+	log.Printf("キューからお題を削除しました: roomId=%s, topic=%s", room.RoomID, room.TopicsPool[position].Text)
+	return topicQueueOf(room.RoomID, pool, room.TopicSubmissions), nil
+}
+
+// moveQueuedTopic - キューのお題を別の位置に移動（ホストのみ）
+// 移動したお題はホストが順番を決めたものとして、先頭に来たら難易度の指定より優先して出題する
+func moveQueuedTopic(ctx context.Context, args map[string]interface{}) (*TopicQueue, error) {
+	room, err := loadQueueRoom(ctx, args, true)
+	if err != nil {
+		return nil, err
+	}
+	position, err := queuedTopicPosition(room.TopicsPool, args, "position")
+	if err != nil {
+		return nil, err
+	}
+	toPosition := 0
+	if v, ok := args["toPosition"].(float64); ok {
+		toPosition = int(v)
+	}
+	if toPosition < 0 || toPosition >= len(room.TopicsPool) {
+		return nil, fmt.Errorf("移動先の位置は0〜%dで指定してください", len(room.TopicsPool)-1)
+	}
+
+	topic := room.TopicsPool[position]
+	topic.Pinned = true
+	pool := append(append([]Topic{}, room.TopicsPool[:position]...), room.TopicsPool[position+1:]...)
+	pool = append(pool[:toPosition], append([]Topic{topic}, pool[toPosition:]...)...)
+
+	if err := saveTopicQueue(ctx, room, pool, room.TopicSubmissions); err != nil {
+		return nil, err
+	}
+	log.Printf("キューのお題を移動しました: roomId=%s, %d→%d, topic=%s", room.RoomID, position, toPosition, topic.Text)
+	return topicQueueOf(room.RoomID, pool, room.TopicSubmissions), nil
+}
+
+// submitTopic - プレイヤーがお題を投稿（ホストが承認するとキューに追加される）
+func submitTopic(ctx context.Context, args map[string]interface{}) (*TopicSubmission, error) {
+	room, err := loadQueueRoom(ctx, args, false)
+	if err != nil {
+		return nil, err
+	}
+	playerID := args["playerId"].(string)
+	player, err := getPlayerItem(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.RoomID != room.RoomID {
+		return nil, fmt.Errorf("このルームのプレイヤーではありません")
+	}
+
+	pending := 0
+	for _, s := range room.TopicSubmissions {
+		if s.PlayerID == playerID {
+			pending++
+		}
+	}
+	if pending >= maxPendingSubmissionsPerPlayer {
+		return nil, fmt.Errorf("承認待ちの投稿は1人%d個までです", maxPendingSubmissionsPerPlayer)
+	}
+	if len(room.TopicSubmissions) >= maxTopicSubmissions {
+		return nil, fmt.Errorf("承認待ちの投稿が上限（%d個）に達しています", maxTopicSubmissions)
+	}
+
+	text, _ := args["topic"].(string)
+	category, _ := args["category"].(string)
+	topic, err := validateCustomTopic(ctx, room, text, category)
+	if err != nil {
+		return nil, err
+	}
+
+	submission := TopicSubmission{
+		SubmissionID: uuid.New().String(),
+		Topic:        topic.Text,
+		PlayerID:     playerID,
+		PlayerName:   player.Name,
+		SubmittedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	if topic.Category != "" {
+		submission.Category = aws.String(topic.Category)
+	}
+	submissions := append(append([]TopicSubmission{}, room.TopicSubmissions...), submission)
+
+	if err := saveTopicQueue(ctx, room, room.TopicsPool, submissions); err != nil {
+		return nil, err
+	}
+	log.Printf("お題が投稿されました: roomId=%s, playerId=%s, topic=%s", room.RoomID, playerID, topic.Text)
+	return &submission, nil
+}
+
+// rejectTopicSubmission - プレイヤーの投稿を却下（ホストのみ）
+func rejectTopicSubmission(ctx context.Context, args map[string]interface{}) (*TopicQueue, error) {
+	room, err := loadQueueRoom(ctx, args, true)
+	if err != nil {
+		return nil, err
+	}
+	submissionID := args["submissionId"].(string)
+	index := findSubmission(room.TopicSubmissions, submissionID)
+	if index < 0 {
+		return nil, fmt.Errorf("投稿が見つかりません")
+	}
+
+	submissions := append(append([]TopicSubmission{}, room.TopicSubmissions[:index]...), room.TopicSubmissions[index+1:]...)
+	if err := saveTopicQueue(ctx, room, room.TopicsPool, submissions); err != nil {
+		return nil, err
+	}
+	log.Printf("投稿を却下しました: roomId=%s, submissionId=%s", room.RoomID, submissionID)
+	return topicQueueOf(room.RoomID, room.TopicsPool, submissions), nil
+}
+
+// loadQueueRoom - キューを操作するルームを取得し、操作できるか確認
+// hostOnlyの場合はホストのみ（共同ホストも不可）に限る
+func loadQueueRoom(ctx context.Context, args map[string]interface{}, hostOnly bool) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return nil, err
+	}
+	if hostOnly && room.HostID != playerID {
+		return nil, fmt.Errorf("ホストのみがお題キューを操作できます")
+	}
+	if room.State == "CLOSED" {
+		return nil, fmt.Errorf("クローズされたルームのお題キューは操作できません")
+	}
+	return room, nil
+}
+
+// validateCustomTopic - ホスト・プレイヤーが入力したお題を検証し、モデレーションを通す
+// モデレーションが使えない場合は不適切なお題を出題しないよう追加を拒否する
+func validateCustomTopic(ctx context.Context, room *Room, text, category string) (Topic, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Topic{}, fmt.Errorf("お題を入力してください")
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return Topic{}, fmt.Errorf("お題に改行は使えません")
+	}
+	if utf8.RuneCountInString(text) > topicgen.MaxTopicLength {
+		return Topic{}, fmt.Errorf("お題は%d文字以内で入力してください", topicgen.MaxTopicLength)
+	}
+	if category != "" && !topicgen.IsCategory(category) {
+		return Topic{}, fmt.Errorf("不明なカテゴリ: %s", category)
+	}
+	if err := checkTopicNotQueued(room, text); err != nil {
+		return Topic{}, err
+	}
+
+	flagged, err := moderateText(ctx, text)
+	if err != nil {
+		log.Printf("警告: お題のモデレーションに失敗: %v", err)
+		return Topic{}, fmt.Errorf("お題の内容を確認できませんでした。しばらくしてから再度お試しください")
+	}
+	if flagged {
+		return Topic{}, fmt.Errorf("不適切な内容が含まれるお題は追加できません")
+	}
+
+	var c *string
+	if category != "" {
+		c = &category
+	}
+	return customTopic(text, c), nil
+}
+
+// checkTopicNotQueued - 出題済み・キューにある・投稿済みのお題でないか確認
+func checkTopicNotQueued(room *Room, text string) error {
+	if (room.Topic != nil && *room.Topic == text) || slices.Contains(room.UsedTopics, text) {
+		return fmt.Errorf("このお題は既に出題されています")
+	}
+	if slices.Contains(topicTexts(room.TopicsPool), text) {
+		return fmt.Errorf("このお題は既にキューにあります")
+	}
+	for _, s := range room.TopicSubmissions {
+		if s.Topic == text {
+			return fmt.Errorf("このお題は既に投稿されています")
+		}
+	}
+	return nil
+}
+
+// customTopic - カスタムのお題（想定回答なし、位置を固定）
+func customTopic(text string, category *string) Topic {
+	t := Topic{Text: text, ExampleAnswers: []string{}, Source: customTopicSource, Pinned: true}
+	if category != nil {
+		t.Category = *category
+	}
+	return t
+}
+
+// queuedTopicPosition - 引数の位置にあるお題が指定されたお題の文と一致するか確認し、位置を返す
+func queuedTopicPosition(pool []Topic, args map[string]interface{}, key string) (int, error) {
+	position := -1
+	if v, ok := args[key].(float64); ok {
+		position = int(v)
+	}
+	if position < 0 || position >= len(pool) {
+		return 0, fmt.Errorf("キューにその位置のお題はありません")
+	}
+	if text, _ := args["topic"].(string); pool[position].Text != text {
+		return 0, fmt.Errorf("キューが更新されました。再度お試しください")
+	}
+	return position, nil
+}
+
+// findSubmission - 投稿IDの位置（ない場合は-1）
+func findSubmission(submissions []TopicSubmission, submissionID string) int {
+	for i, s := range submissions {
+		if s.SubmissionID == submissionID {
+			return i
+		}
+	}
+	return -1
+}
+
+// topicQueueOf - ホストに返すキューの内容
+func topicQueueOf(roomID string, pool []Topic, submissions []TopicSubmission) *TopicQueue {
+	queue := &TopicQueue{
+		RoomID:      roomID,
+		Topics:      make([]QueuedTopic, 0, len(pool)),
+		Submissions: submissions,
+	}
+	if queue.Submissions == nil {
+		queue.Submissions = []TopicSubmission{}
+	}
+	for i, t := range pool {
+		q := QueuedTopic{Position: i, Topic: t.Text, Source: "AI"}
+		if t.Category != "" {
+			q.Category = aws.String(t.Category)
+		}
+		if t.Source != "" {
+			q.Source = t.Source
+		}
+		if t.SubmittedBy != "" {
+			q.SubmittedBy = aws.String(t.SubmittedBy)
+		}
+		queue.Topics = append(queue.Topics, q)
+	}
+	return queue
+}
+
+// saveTopicQueue - お題プールと投稿を保存
+// 読み込んだ時点から版が進んでいた場合（nextRound・補充・他の操作が先に保存した場合）は保存せずにエラーを返す
+// 件数の比較では、並べ替えや「1個出題して1個補充」のように件数が変わらない更新を上書きしてしまうため、版で比較する
+func saveTopicQueue(ctx context.Context, room *Room, pool []Topic, submissions []TopicSubmission) error {
+	topics, err := marshalTopicList(pool)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{
+		"#topicsPool":       "topicsPool",
+		"#topicSubmissions": "topicSubmissions",
+		"#updatedAt":        "updatedAt",
+	}
+	values := map[string]types.AttributeValue{
+		":topicsPool": topics,
+		":updatedAt":  &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+	}
+
+	// 版は最初の書き込みで1になる（0は属性がない状態）
+	cond := "attribute_not_exists(#queueVersion)"
+	if room.QueueVersion > 0 {
+		values[":queueVersion"] = &types.AttributeValueMemberN{Value: strconv.Itoa(room.QueueVersion)}
+		cond = "#queueVersion = :queueVersion"
+	}
+
+	expr := "SET #topicsPool = :topicsPool, #updatedAt = :updatedAt"
+	if len(submissions) > 0 {
+		av, err := attributevalue.Marshal(submissions)
+		if err != nil {
+			return fmt.Errorf("投稿のマーシャルに失敗: %w", err)
+		}
+		values[":topicSubmissions"] = av
+		expr += ", #topicSubmissions = :topicSubmissions"
+	} else {
+		expr += " REMOVE #topicSubmissions"
+	}
+	expr += queueVersionUpdate(names, values)
+
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: room.RoomID},
+		},
+		UpdateExpression:          aws.String(expr),
+		ConditionExpression:       aws.String(cond),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return fmt.Errorf("キューが更新されました。再度お試しください")
+		}
+		return fmt.Errorf("お題キューの保存に失敗: %w", err)
+	}
+	return nil
+}
+
+// queueVersionUpdate - お題プールの版を1つ進める更新式（お題プール・投稿を書き換えるすべての更新の末尾に付ける）
+func queueVersionUpdate(names map[string]string, values map[string]types.AttributeValue) string {
+	names["#queueVersion"] = "queueVersion"
+	values[":queueVersionStep"] = &types.AttributeValueMemberN{Value: "1"}
+	return " ADD #queueVersion :queueVersionStep"
+}
+
+// pinnedTopics - プールのうちホストが位置を指定したお題（ゲームを始め直しても残す）
+func pinnedTopics(pool []Topic) []Topic {
+	pinned := []Topic{}
+	for _, t := range pool {
+		if t.Pinned {
+			pinned = append(pinned, t)
+		}
+	}
+	return pinned
+}
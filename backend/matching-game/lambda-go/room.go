@@ -3,9 +3,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -14,30 +18,64 @@ import (
 	"github.com/google/uuid"
 )
 
+// maxNameLength - プレイヤー名の最大文字数
+const maxNameLength = 20
+
 // createRoom - 新しいゲームルームを作成
 // ホストとなるプレイヤーも同時に作成する
 func createRoom(ctx context.Context, args map[string]interface{}) (*Room, error) {
-	hostName := args["hostName"].(string)
+	hostName, err := validateName(args["hostName"].(string))
+	if err != nil {
+		return nil, err
+	}
+	deviceToken, _ := args["deviceToken"].(string)
+
+	// ゲームの種類（省略時は認識合わせ）
+	gameType := defaultGameType
+	if v, ok := args["gameType"].(string); ok && v != "" {
+		gameType = v
+	}
+	if _, err := lookupGame(gameType); err != nil {
+		return nil, err
+	}
+
+	// ルーム設定（省略時は既定値）
+	settings := defaultRoomSettings()
+	input, _ := args["settings"].(map[string]interface{})
+	settings, err = applySettingsInput(ctx, settings, input)
+	if err != nil {
+		return nil, err
+	}
 
 	// ID生成
 	roomID := uuid.New().String()
 	playerID := uuid.New().String()
-	roomCode := generateRoomCode()
+	roomCode, err := generateUniqueRoomCode(ctx)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now().UTC().Format(time.RFC3339)
-	ttl := time.Now().Unix() + 86400 // 24時間後に自動削除
+	ttl := newRoomTTL() // 最後の活動から24時間後に自動削除
 
 	// ルームデータを作成
 	room := Room{
-		RoomID:     roomID,
-		RoomCode:   roomCode,
-		HostID:     playerID,
-		State:      "WAITING", // 待機状態で開始
-		TopicsPool: []string{},
-		UsedTopics: []string{},
-		Comments:   []string{},
-		CreatedAt:  now,
-		UpdatedAt:  now,
-		TTL:        ttl,
+		RoomID:          roomID,
+		RoomCode:        roomCode,
+		HostID:          playerID,
+		GameType:        gameType,
+		State:           "WAITING", // 待機状態で開始
+		TopicsPool:      []Topic{},
+		TopicsPoolTexts: []string{},
+		UsedTopics:      []string{},
+		Comments:        []string{},
+		Visibility:      defaultVisibility,
+		Settings:        &settings,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		TTL:             ttl,
+	}
+	if err := applyAccessInput(&room, input); err != nil {
+		return nil, err
 	}
 
 	// DynamoDBにルームを保存
@@ -56,12 +94,15 @@ func createRoom(ctx context.Context, args map[string]interface{}) (*Room, error)
 
 	// ホストプレイヤーを作成
 	player := Player{
-		PlayerID:  playerID,
-		RoomID:    roomID,
-		Name:      hostName,
-		Role:      "HOST",
-		Connected: true,
-		JoinedAt:  now,
+		PlayerID:    playerID,
+		RoomID:      roomID,
+		Name:        hostName,
+		Role:        "HOST",
+		Connected:   true,
+		JoinedAt:    now,
+		TTL:         ttl,
+		IdentityID:  callerIdentity(ctx),
+		DeviceToken: deviceToken,
 	}
 
 	playerItem, err := attributevalue.MarshalMap(player)
@@ -87,7 +128,12 @@ func createRoom(ctx context.Context, args map[string]interface{}) (*Room, error)
 // joinRoom - 既存のルームに参加
 func joinRoom(ctx context.Context, args map[string]interface{}) (*Player, error) {
 	roomCode := args["roomCode"].(string)
-	playerName := args["playerName"].(string)
+	playerName, err := validateName(args["playerName"].(string))
+	if err != nil {
+		return nil, err
+	}
+	deviceToken, _ := args["deviceToken"].(string)
+	password, _ := args["password"].(string)
 
 	// ルームコードからルームを検索
 	room, err := getRoomByCode(ctx, map[string]interface{}{"roomCode": roomCode})
@@ -98,18 +144,147 @@ func joinRoom(ctx context.Context, args map[string]interface{}) (*Player, error)
 		return nil, fmt.Errorf("ルームが見つかりません")
 	}
 
+	// パスワード付きのルームはパスワードを確認
+	if !checkRoomPassword(room, password) {
+		return nil, fmt.Errorf("パスワードが正しくありません")
+	}
+
+	return addPlayerToRoom(ctx, room, playerName, deviceToken, joinRole(room))
+}
+
+// joinAsSpectator - 観戦者としてルームに参加
+// 観戦者は全てのSubscriptionを受信できるが、回答・判定・得点には含まれない
+func joinAsSpectator(ctx context.Context, args map[string]interface{}) (*Player, error) {
+	roomCode := args["roomCode"].(string)
+	playerName, err := validateName(args["playerName"].(string))
+	if err != nil {
+		return nil, err
+	}
+	deviceToken, _ := args["deviceToken"].(string)
+	password, _ := args["password"].(string)
+
+	room, err := getRoomByCode(ctx, map[string]interface{}{"roomCode": roomCode})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	if !checkRoomPassword(room, password) {
+		return nil, fmt.Errorf("パスワードが正しくありません")
+	}
+
+	return addPlayerToRoom(ctx, room, playerName, deviceToken, "SPECTATOR")
+}
+
+// joinRole - 通常の参加で割り当てる役割を決定
+// 満員のルームに参加した場合は観戦者になる
+func joinRole(room *Room) string {
+	if room.Settings.MaxPlayers > 0 && countActivePlayers(room.Players) >= room.Settings.MaxPlayers {
+		log.Printf("満員のため観戦者として参加: roomId=%s", room.RoomID)
+		return "SPECTATOR"
+	}
+	return "PLAYER"
+}
+
+// isRoundInProgress - ラウンド進行中か（進行中の状態はゲームの種類ごとに異なる）
+func isRoundInProgress(room *Room) bool {
+	game, err := lookupGame(room.GameType)
+	if err != nil {
+		return false
+	}
+	return game.IsRoundInProgress(room.State)
+}
+
+// activateWaitingPlayers - 次のラウンド待ちのプレイヤーを参加状態に戻す
+// nextRound・endGameから呼ばれる
+func activateWaitingPlayers(ctx context.Context, players []Player) {
+	for _, p := range players {
+		if !p.WaitingForNextRound {
+			continue
+		}
+		_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(playerTable),
+			Key: map[string]types.AttributeValue{
+				"playerId": &types.AttributeValueMemberS{Value: p.PlayerID},
+			},
+			UpdateExpression: aws.String("SET #waitingForNextRound = :waiting"),
+			ExpressionAttributeNames: map[string]string{
+				"#waitingForNextRound": "waitingForNextRound",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":waiting": &types.AttributeValueMemberBOOL{Value: false},
+			},
+		})
+		if err != nil {
+			log.Printf("警告: 次のラウンド待ちの解除に失敗 %s: %v", p.PlayerID, err)
+		}
+	}
+}
+
+// countActivePlayers - 観戦者を除いた参加人数（最大人数の判定に使用）
+func countActivePlayers(players []Player) int {
+	count := 0
+	for _, p := range players {
+		if p.Role != "SPECTATOR" {
+			count++
+		}
+	}
+	return count
+}
+
+// checkCanJoin - BANを確認（参加経路によらず共通）
+func checkCanJoin(ctx context.Context, room *Room, deviceToken string) error {
+	// 追放された端末からの再参加を拒否
+	if isBanned(room, callerIdentity(ctx), deviceToken) {
+		return fmt.Errorf("このルームへの参加は許可されていません")
+	}
+
+	return nil
+}
+
+// addPlayerToRoom - 指定した役割でルームにプレイヤーを追加（参加系のMutationで共通）
+func addPlayerToRoom(ctx context.Context, room *Room, playerName, deviceToken, role string) (*Player, error) {
+	if err := checkCanJoin(ctx, room, deviceToken); err != nil {
+		return nil, err
+	}
+
+	// 同じ名前のプレイヤーがいる場合は番号を付けて区別する
+	playerName = uniqueName(playerName, room.Players, "")
+
+	// ラウンド途中に参加したプレイヤーは、お題を見ていない現在のラウンドの判定に含めない
+	waiting := role != "SPECTATOR" && isRoundInProgress(room)
+	if waiting {
+		log.Printf("ラウンド進行中のため次のラウンドから参加: roomId=%s, state=%s", room.RoomID, room.State)
+	}
+
+	// チーム戦では人数の少ないチームに入れる
+	team := 0
+	if room.Settings.TeamCount > 0 && role != "SPECTATOR" {
+		team = smallestTeam(room.Players, room.Settings.TeamCount)
+	}
+
+	// 参加はルームの活動とみなしてTTLを延長
+	extendRoomTTL(ctx, room)
+
 	// プレイヤーを作成
 	playerID := uuid.New().String()
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	player := Player{
-		PlayerID:  playerID,
-		RoomID:    room.RoomID,
-		RoomCode:  roomCode, // Subscriptionフィルタ用にroomCodeを含める
-		Name:      playerName,
-		Role:      "PLAYER", // 一般プレイヤー
-		Connected: true,
-		JoinedAt:  now,
+		PlayerID:            playerID,
+		RoomID:              room.RoomID,
+		RoomCode:            room.RoomCode, // Subscriptionフィルタ用にroomCodeを含める
+		Name:                playerName,
+		Role:                role, // PLAYERまたはSPECTATOR
+		Team:                team,
+		WaitingForNextRound: waiting,
+		Connected:           true,
+		JoinedAt:            now,
+		TTL:                 room.TTL,
+		IdentityID:          callerIdentity(ctx),
+		DeviceToken:         deviceToken,
 	}
 
 	playerItem, err := attributevalue.MarshalMap(player)
@@ -129,21 +304,207 @@ func joinRoom(ctx context.Context, args map[string]interface{}) (*Player, error)
 }
 
 // leaveRoom - ルームから退出
-func leaveRoom(ctx context.Context, args map[string]interface{}) (bool, error) {
+// 最後のプレイヤーが退出した場合はルームをクローズし、ルームコードを解放する
+// ホストが退出した場合は、共同ホスト（いない場合は最も早く参加したプレイヤー）をホストにする
+func leaveRoom(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
 	playerID := args["playerId"].(string)
 
-	// プレイヤーを削除
-	_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	var leaving *Player
+	var remaining []Player
+	for i := range room.Players {
+		if room.Players[i].PlayerID == playerID {
+			leaving = &room.Players[i]
+		} else {
+			remaining = append(remaining, room.Players[i])
+		}
+	}
+	if leaving == nil {
+		return nil, fmt.Errorf("このルームのプレイヤーではありません")
+	}
+
+	// プレイヤーを削除（別のルームのプレイヤーを消さないよう、ルームIDも条件にする）
+	deletePlayer := &types.Delete{
 		TableName: aws.String(playerTable),
 		Key: map[string]types.AttributeValue{
 			"playerId": &types.AttributeValueMemberS{Value: playerID},
 		},
+		ConditionExpression: aws.String("#roomId = :roomId"),
+		ExpressionAttributeNames: map[string]string{
+			"#roomId": "roomId",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+	}
+
+	if room.HostID != playerID || len(remaining) == 0 {
+		_, err = ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:                 deletePlayer.TableName,
+			Key:                       deletePlayer.Key,
+			ConditionExpression:       deletePlayer.ConditionExpression,
+			ExpressionAttributeNames:  deletePlayer.ExpressionAttributeNames,
+			ExpressionAttributeValues: deletePlayer.ExpressionAttributeValues,
+		})
+		if err != nil {
+			var condErr *types.ConditionalCheckFailedException
+			if errors.As(err, &condErr) {
+				return nil, fmt.Errorf("このルームのプレイヤーではありません")
+			}
+			return nil, fmt.Errorf("プレイヤーの削除に失敗: %w", err)
+		}
+	} else {
+		// ホストの退出・ホストの交代・新しいホストの役割の変更を1つのトランザクションで行う
+		newHost := nextHost(remaining)
+		log.Printf("ホストが退出したためホストを交代: roomId=%s, %s → %s", roomID, playerID, newHost.PlayerID)
+		_, err = ddbClient.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{Delete: deletePlayer},
+				{Update: &types.Update{
+					TableName: aws.String(roomTable),
+					Key: map[string]types.AttributeValue{
+						"roomId": &types.AttributeValueMemberS{Value: roomID},
+					},
+					UpdateExpression:    aws.String("SET #hostId = :newHostId, #updatedAt = :updatedAt"),
+					ConditionExpression: aws.String("#hostId = :hostId"),
+					ExpressionAttributeNames: map[string]string{
+						"#hostId":    "hostId",
+						"#updatedAt": "updatedAt",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":newHostId": &types.AttributeValueMemberS{Value: newHost.PlayerID},
+						":hostId":    &types.AttributeValueMemberS{Value: playerID},
+						":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+					},
+				}},
+				{Update: &types.Update{
+					TableName: aws.String(playerTable),
+					Key: map[string]types.AttributeValue{
+						"playerId": &types.AttributeValueMemberS{Value: newHost.PlayerID},
+					},
+					UpdateExpression:    aws.String("SET #role = :role"),
+					ConditionExpression: aws.String("#roomId = :roomId"),
+					ExpressionAttributeNames: map[string]string{
+						"#role":   "role",
+						"#roomId": "roomId",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":role":   &types.AttributeValueMemberS{Value: "HOST"},
+						":roomId": &types.AttributeValueMemberS{Value: roomID},
+					},
+				}},
+			},
+		})
+		if err != nil {
+			var canceledErr *types.TransactionCanceledException
+			if errors.As(err, &canceledErr) {
+				return nil, fmt.Errorf("ルームが更新されました。再度お試しください")
+			}
+			return nil, fmt.Errorf("ホストの退出に失敗: %w", err)
+		}
+	}
+
+	// 退出後のルーム情報を取得
+	room, err = getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	// 誰もいなくなったらルームをクローズ
+	if len(room.Players) == 0 {
+		return closeEmptyRoom(ctx, room)
+	}
+
+	return room, nil
+}
+
+// nextHost - ホストが退出した後のホスト（共同ホスト、観戦者以外、観戦者の順に、同じ中では最も早く参加したプレイヤー）
+func nextHost(players []Player) Player {
+	rank := func(p Player) int {
+		switch p.Role {
+		case "COHOST":
+			return 0
+		case "SPECTATOR":
+			return 2
+		}
+		return 1
+	}
+	best := players[0]
+	for _, p := range players[1:] {
+		if r, br := rank(p), rank(best); r < br || (r == br && p.JoinedAt < best.JoinedAt) {
+			best = p
+		}
+	}
+	return best
+}
+
+// closeEmptyRoom - 空になったルームをCLOSED状態にしてアーカイブ
+// roomCodeを削除してGSIから外すことで、同じコードを新しいルームで再利用できるようにする
+// visibilityも削除してロビーの一覧から外す
+func closeEmptyRoom(ctx context.Context, room *Room) (*Room, error) {
+	log.Printf("最後のプレイヤーが退出したためルームをクローズ: roomId=%s, roomCode=%s", room.RoomID, room.RoomCode)
+
+	// 残っている回答は不要なので削除
+	for _, answer := range room.Answers {
+		_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(answerTable),
+			Key: map[string]types.AttributeValue{
+				"answerId": &types.AttributeValueMemberS{Value: answer.AnswerID},
+			},
+		})
+		if err != nil {
+			log.Printf("警告: 回答の削除に失敗 %s: %v", answer.AnswerID, err)
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	ttl := time.Now().Unix() + closedRoomTTLSeconds
+
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: room.RoomID},
+		},
+		UpdateExpression: aws.String("SET #state = :state, #closedAt = :closedAt, #updatedAt = :updatedAt, #ttl = :ttl REMOVE #roomCode, #topic, #visibility"),
+		ExpressionAttributeNames: map[string]string{
+			"#state":      "state",
+			"#closedAt":   "closedAt",
+			"#updatedAt":  "updatedAt",
+			"#ttl":        "ttl",
+			"#roomCode":   "roomCode",
+			"#topic":      "topic",
+			"#visibility": "visibility",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":state":     &types.AttributeValueMemberS{Value: "CLOSED"},
+			":closedAt":  &types.AttributeValueMemberS{Value: now},
+			":updatedAt": &types.AttributeValueMemberS{Value: now},
+			":ttl":       &types.AttributeValueMemberN{Value: strconv.FormatInt(ttl, 10)},
+		},
 	})
 	if err != nil {
-		return false, fmt.Errorf("プレイヤーの削除に失敗: %w", err)
+		return nil, fmt.Errorf("ルームのクローズに失敗: %w", err)
 	}
 
-	return true, nil
+	// 購読者への最終通知用にroomCodeは残したまま返す
+	room.State = "CLOSED"
+	room.ClosedAt = &now
+	room.UpdatedAt = now
+	room.TTL = ttl
+	room.Topic = nil
+	room.Answers = []Answer{}
+
+	return room, nil
 }
 
 // kickPlayer - プレイヤーを追放（ホストのみ）
@@ -173,6 +534,24 @@ func kickPlayer(ctx context.Context, args map[string]interface{}) (*Room, error)
 		return nil, fmt.Errorf("自分自身を追放することはできません")
 	}
 
+	// 追放対象をBANリストに追加（同じ端末からの再参加を防ぐ）
+	for _, p := range room.Players {
+		if p.PlayerID != kickedPlayerID {
+			continue
+		}
+		ban := Ban{
+			BanID:       uuid.New().String(),
+			Name:        p.Name,
+			IdentityID:  p.IdentityID,
+			DeviceToken: p.DeviceToken,
+			BannedAt:    time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := addBan(ctx, roomID, ban); err != nil {
+			return nil, err
+		}
+		break
+	}
+
 	// プレイヤーを削除
 	_, err = ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
 		TableName: aws.String(playerTable),
@@ -215,89 +594,447 @@ func kickPlayer(ctx context.Context, args map[string]interface{}) (*Room, error)
 	return updatedRoom, nil
 }
 
-// deleteAllData - 全データを削除（開発用）
-func deleteAllData(ctx context.Context) (*DeleteAllDataResponse, error) {
-	log.Println("全データ削除を開始")
+// unbanPlayer - BANを解除（ホストのみ）
+func unbanPlayer(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	banID := args["banId"].(string)
 
-	deletedCounts := DeletedCounts{}
+	log.Printf("BAN解除: roomId=%s, playerId=%s, banId=%s", roomID, playerID, banID)
 
-	// 回答を全削除
-	answersResult, err := ddbClient.Scan(ctx, &dynamodb.ScanInput{
-		TableName: aws.String(answerTable),
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	// ホストのみ解除可能
+	if room.HostID != playerID {
+		return nil, fmt.Errorf("ホストのみがBANを解除できます")
+	}
+
+	// 対象を除いたBANリストを作成
+	remaining := []Ban{}
+	found := false
+	for _, ban := range room.BanList {
+		if ban.BanID == banID {
+			found = true
+			continue
+		}
+		remaining = append(remaining, ban)
+	}
+	if !found {
+		return nil, fmt.Errorf("指定されたBANが見つかりません")
+	}
+
+	banList, err := attributevalue.Marshal(remaining)
+	if err != nil {
+		return nil, fmt.Errorf("BANリストのマーシャルに失敗: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+		UpdateExpression: aws.String("SET #banList = :banList, #updatedAt = :updatedAt"),
+		ExpressionAttributeNames: map[string]string{
+			"#banList":   "banList",
+			"#updatedAt": "updatedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":banList":   banList,
+			":updatedAt": &types.AttributeValueMemberS{Value: now},
+		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("回答のスキャンに失敗: %w", err)
+		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
 	}
 
-	for _, item := range answersResult.Items {
-		var answer Answer
-		if err := attributevalue.UnmarshalMap(item, &answer); err != nil {
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
+
+// setReady - 準備完了フラグを変更（WAITING中のみ）
+// ルーム設定でrequireAllReadyが有効な場合、startGameは全員の準備完了を条件とする
+func setReady(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	ready := args["ready"].(bool)
+
+	log.Printf("準備完了変更: roomId=%s, playerId=%s, ready=%v", roomID, playerID, ready)
+
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if room.State != "WAITING" {
+		return nil, fmt.Errorf("準備完了はゲーム開始前のみ変更できます")
+	}
+
+	player, err := getPlayerItem(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if player == nil || player.RoomID != roomID {
+		return nil, fmt.Errorf("プレイヤーが見つかりません")
+	}
+
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(playerTable),
+		Key: map[string]types.AttributeValue{
+			"playerId": &types.AttributeValueMemberS{Value: playerID},
+		},
+		UpdateExpression: aws.String("SET #ready = :ready"),
+		ExpressionAttributeNames: map[string]string{
+			"#ready": "ready",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ready": &types.AttributeValueMemberBOOL{Value: ready},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("プレイヤーの更新に失敗: %w", err)
+	}
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
+
+// checkReadyToStart - ゲーム開始の条件（最小人数・全員の準備完了）を確認
+// 観戦者は数えず、開始操作をするホストは準備完了とみなす
+func checkReadyToStart(room *Room) error {
+	active := countActivePlayers(room.Players)
+	if room.Settings.MinPlayers > 0 && active < room.Settings.MinPlayers {
+		return fmt.Errorf("ゲーム開始には%d人以上の参加が必要です（現在%d人）", room.Settings.MinPlayers, active)
+	}
+
+	if room.Settings.RequireAllReady {
+		notReady := []string{}
+		for _, p := range room.Players {
+			if p.Role == "SPECTATOR" || p.PlayerID == room.HostID || p.Ready {
+				continue
+			}
+			notReady = append(notReady, p.Name)
+		}
+		if len(notReady) > 0 {
+			return fmt.Errorf("準備完了していないプレイヤーがいます: %s", strings.Join(notReady, "、"))
+		}
+	}
+
+	return nil
+}
+
+// resetReady - 全プレイヤーの準備完了フラグを解除（ゲーム開始時に呼ぶ）
+func resetReady(ctx context.Context, players []Player) {
+	for _, p := range players {
+		if !p.Ready {
 			continue
 		}
-		_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-			TableName: aws.String(answerTable),
+		_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(playerTable),
 			Key: map[string]types.AttributeValue{
-				"answerId": &types.AttributeValueMemberS{Value: answer.AnswerID},
+				"playerId": &types.AttributeValueMemberS{Value: p.PlayerID},
+			},
+			UpdateExpression: aws.String("SET #ready = :ready"),
+			ExpressionAttributeNames: map[string]string{
+				"#ready": "ready",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":ready": &types.AttributeValueMemberBOOL{Value: false},
 			},
 		})
-		if err == nil {
-			deletedCounts.Answers++
+		if err != nil {
+			log.Printf("警告: 準備完了のリセットに失敗 %s: %v", p.PlayerID, err)
+		}
+	}
+}
+
+// setCohost - 共同ホストの付与・解除（ホストのみ）
+// 共同ホストは判定・次ラウンド・お題スキップを操作できるが、ゲーム終了はホストのみ
+func setCohost(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	targetPlayerID := args["targetPlayerId"].(string)
+	enabled := args["enabled"].(bool)
+
+	log.Printf("共同ホスト変更: roomId=%s, playerId=%s, targetPlayerId=%s, enabled=%v", roomID, playerID, targetPlayerID, enabled)
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	// ホストのみ変更可能
+	if room.HostID != playerID {
+		return nil, fmt.Errorf("ホストのみが共同ホストを変更できます")
+	}
+	if targetPlayerID == room.HostID {
+		return nil, fmt.Errorf("ホスト自身を共同ホストにすることはできません")
+	}
+
+	var target *Player
+	for i := range room.Players {
+		if room.Players[i].PlayerID == targetPlayerID {
+			target = &room.Players[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("プレイヤーが見つかりません")
+	}
+	if target.Role == "SPECTATOR" {
+		return nil, fmt.Errorf("観戦者を共同ホストにすることはできません")
+	}
+
+	role := "PLAYER"
+	if enabled {
+		role = "COHOST"
+	}
+
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(playerTable),
+		Key: map[string]types.AttributeValue{
+			"playerId": &types.AttributeValueMemberS{Value: targetPlayerID},
+		},
+		UpdateExpression: aws.String("SET #role = :role"),
+		ExpressionAttributeNames: map[string]string{
+			"#role": "role",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":role": &types.AttributeValueMemberS{Value: role},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("プレイヤーの更新に失敗: %w", err)
+	}
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
+
+// requireGameControl - ゲーム進行の操作権限を確認（ホストまたは共同ホスト）
+func requireGameControl(ctx context.Context, room *Room, playerID string) error {
+	if room.HostID == playerID {
+		return nil
+	}
+
+	player, err := getPlayerItem(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if player != nil && player.RoomID == room.RoomID && player.Role == "COHOST" {
+		return nil
+	}
+
+	return fmt.Errorf("ホストまたは共同ホストのみが操作できます")
+}
+
+// requireGameType - ルームのゲームの種類で使用できる操作か確認
+func requireGameType(room *Room, gameType string) error {
+	if room.GameType != gameType {
+		return fmt.Errorf("このルームのゲーム（%s）では使用できない操作です", room.GameType)
+	}
+	return nil
+}
+
+// renamePlayer - プレイヤー名を変更
+// 現在のラウンドの回答に保存されているプレイヤー名も合わせて更新する
+func renamePlayer(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	newName, err := validateName(args["newName"].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("名前変更: roomId=%s, playerId=%s, newName=%s", roomID, playerID, newName)
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	found := false
+	for _, p := range room.Players {
+		if p.PlayerID == playerID {
+			found = true
+			break
 		}
 	}
+	if !found {
+		return nil, fmt.Errorf("プレイヤーが見つかりません")
+	}
 
-	// プレイヤーを全削除
-	playersResult, err := ddbClient.Scan(ctx, &dynamodb.ScanInput{
+	// 名前変更は本人の明示的な操作なので、重複時は自動で番号を付けずにエラーとする
+	if uniqueName(newName, room.Players, playerID) != newName {
+		return nil, fmt.Errorf("「%s」は既にこのルームで使われています", newName)
+	}
+
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(playerTable),
+		Key: map[string]types.AttributeValue{
+			"playerId": &types.AttributeValueMemberS{Value: playerID},
+		},
+		UpdateExpression: aws.String("SET #name = :name"),
+		ExpressionAttributeNames: map[string]string{
+			"#name": "name",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":name": &types.AttributeValueMemberS{Value: newName},
+		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("プレイヤーのスキャンに失敗: %w", err)
+		return nil, fmt.Errorf("プレイヤーの更新に失敗: %w", err)
 	}
 
-	for _, item := range playersResult.Items {
-		var player Player
-		if err := attributevalue.UnmarshalMap(item, &player); err != nil {
+	// 提出済みの回答のプレイヤー名も更新（コメント生成で使われるため）
+	for _, answer := range room.Answers {
+		if answer.PlayerID != playerID {
 			continue
 		}
-		_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-			TableName: aws.String(playerTable),
+		_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(answerTable),
 			Key: map[string]types.AttributeValue{
-				"playerId": &types.AttributeValueMemberS{Value: player.PlayerID},
+				"answerId": &types.AttributeValueMemberS{Value: answer.AnswerID},
+			},
+			UpdateExpression: aws.String("SET #playerName = :playerName"),
+			ExpressionAttributeNames: map[string]string{
+				"#playerName": "playerName",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":playerName": &types.AttributeValueMemberS{Value: newName},
 			},
 		})
-		if err == nil {
-			deletedCounts.Players++
+		if err != nil {
+			log.Printf("警告: 回答のプレイヤー名更新に失敗 %s: %v", answer.AnswerID, err)
 		}
 	}
 
-	// ルームを全削除
-	roomsResult, err := ddbClient.Scan(ctx, &dynamodb.ScanInput{
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
+
+// addBan - ルームのBANリストに追放記録を追加
+func addBan(ctx context.Context, roomID string, ban Ban) error {
+	banItem, err := attributevalue.Marshal(ban)
+	if err != nil {
+		return fmt.Errorf("BANのマーシャルに失敗: %w", err)
+	}
+
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+		UpdateExpression: aws.String("SET #banList = list_append(if_not_exists(#banList, :empty), :ban)"),
+		ExpressionAttributeNames: map[string]string{
+			"#banList": "banList",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":empty": &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			":ban":   &types.AttributeValueMemberL{Value: []types.AttributeValue{banItem}},
+		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("ルームのスキャンに失敗: %w", err)
+		return fmt.Errorf("BANリストの更新に失敗: %w", err)
 	}
+	return nil
+}
 
-	for _, item := range roomsResult.Items {
-		var room Room
-		if err := attributevalue.UnmarshalMap(item, &room); err != nil {
-			continue
+// isBanned - 参加者の端末がBANリストに含まれるか判定
+// Cognito Identity IDまたは端末トークンのどちらかが一致すればBAN対象とする
+func isBanned(room *Room, identityID, deviceToken string) bool {
+	for _, ban := range room.BanList {
+		if identityID != "" && ban.IdentityID == identityID {
+			return true
 		}
-		_, err := ddbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-			TableName: aws.String(roomTable),
-			Key: map[string]types.AttributeValue{
-				"roomId": &types.AttributeValueMemberS{Value: room.RoomID},
-			},
-		})
-		if err == nil {
-			deletedCounts.Rooms++
+		if deviceToken != "" && ban.DeviceToken == deviceToken {
+			return true
 		}
 	}
+	return false
+}
+
+// validateName - プレイヤー名の前後の空白を除去し、長さを検証
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("名前を入力してください")
+	}
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", fmt.Errorf("名前は%d文字以内で入力してください", maxNameLength)
+	}
+	return name, nil
+}
 
-	log.Printf("全データ削除完了: %+v", deletedCounts)
+// normalizeName - 名前の重複判定用に表記ゆれを吸収
+// 全角英数字・全角スペースを半角に揃え、大文字小文字と連続する空白を区別しない
+func normalizeName(name string) string {
+	folded := strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u3000':
+			return ' '
+		case r >= '\uFF01' && r <= '\uFF5E':
+			return r - 0xFEE0
+		}
+		return r
+	}, name)
+	return strings.ToLower(strings.Join(strings.Fields(folded), " "))
+}
 
-	return &DeleteAllDataResponse{
-		Success:       true,
-		Message:       "全データを削除しました",
-		DeletedCounts: deletedCounts,
-	}, nil
+// uniqueName - ルーム内で重複しない名前を返す
+// 正規化後に同じ名前がいれば「たろう(2)」のように番号を付ける。excludePlayerIDのプレイヤーは比較対象外
+// 番号を付けてもmaxNameLengthを超えないよう、元の名前を番号の分だけ切り詰める
+func uniqueName(name string, players []Player, excludePlayerID string) string {
+	taken := make(map[string]bool)
+	for _, p := range players {
+		if p.PlayerID == excludePlayerID {
+			continue
+		}
+		taken[normalizeName(p.Name)] = true
+	}
+
+	candidate := name
+	for n := 2; taken[normalizeName(candidate)]; n++ {
+		suffix := fmt.Sprintf("(%d)", n)
+		base := []rune(name)
+		if limit := maxNameLength - utf8.RuneCountInString(suffix); len(base) > limit {
+			base = base[:limit]
+		}
+		candidate = string(base) + suffix
+	}
+	return candidate
 }
@@ -0,0 +1,417 @@
+// settings.go - ルーム設定（人数・準備確認・ラウンド数・制限時間・お題・デッキ・難易度・コメント・得点ルール・ワードウルフ・公開設定）
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"golang.org/x/crypto/scrypt"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+// 設定値の上限・既定値
+const (
+	maxPlayersLimit      = 100  // 最大人数の上限
+	maxRoundsLimit       = 100  // ラウンド数の上限
+	answerTimeLimitMin   = 10   // 回答制限時間の下限（秒、0は無制限）
+	answerTimeLimitMax   = 600  // 回答制限時間の上限（秒）
+	answerDeadlineGraceS = 2    // 締め切り判定の猶予（秒、通信遅延を考慮）
+	discussionTimeMin    = 30   // ワードウルフの議論時間の下限（秒、0は無制限）
+	discussionTimeMax    = 1800 // ワードウルフの議論時間の上限（秒）
+	maxWolfCount         = 3    // ワードウルフの少数派の人数の上限
+	maxRoomTopicDecks    = 10   // 1ルームで選択できるデッキの数
+
+	maxPasswordLength = 32 // 参加パスワードの最大文字数
+
+	// 参加パスワードのハッシュ（scrypt）のパラメータ
+	// 128MBのLambdaでも1回数十msで計算できる強さにし、DBの内容が漏れても総当たりで短いパスワードを割り出しにくくする
+	passwordHashPrefix = "scrypt$" // scryptのハッシュの接頭辞（接頭辞のないハッシュは旧形式のSHA-256）
+	scryptN            = 1 << 14   // CPU・メモリのコスト（メモリは約16MB）
+	scryptR            = 8         // ブロックサイズ
+	scryptP            = 1         // 並列度
+	scryptKeyLength    = 32        // ハッシュの長さ（バイト）
+
+	defaultVisibility  = "PRIVATE"   // 公開設定（既定はルームコードを知っている人のみ）
+	defaultTopicSource = "AI"        // お題の出典（既定はAI生成）
+	defaultScoringRule = "ALL_MATCH" // 得点ルール（既定は全員一致で1点）
+	defaultGameType    = "MATCHING"  // ゲームの種類（既定は認識合わせ）
+
+	defaultDiscussionTime = 180 // ワードウルフの議論時間の既定値（秒）
+	defaultWolfCount      = 1   // ワードウルフの少数派の人数の既定値
+)
+
+// validVisibilities - 選択可能な公開設定
+var validVisibilities = map[string]bool{
+	"PRIVATE": true, // ルームコードを知っている人のみ参加可能
+	"PUBLIC":  true, // ロビー（listPublicRooms）に表示される
+}
+
+// validTopicSources - 選択可能なお題の出典
+var validTopicSources = map[string]bool{
+	"AI":    true, // OpenAIで生成（お題バンク）
+	"DECK":  true, // 選択したデッキのお題のみ（deck.go）
+	"MIXED": true, // 選択したデッキとお題バンクのお題を交互に出題
+}
+
+// validScoringRules - 選択可能な得点ルール
+var validScoringRules = map[string]bool{
+	"ALL_MATCH": true, // 全員一致したラウンドで1点
+	"MAJORITY":  true, // 最も人数の多い回答グループのメンバーが個人で得点（majority.go）
+	"NONE":      true, // 得点を記録しない
+}
+
+// defaultRoomSettings - 設定を省略した場合の既定値（従来の挙動と同じ）
+func defaultRoomSettings() RoomSettings {
+	return RoomSettings{
+		MaxPlayers:      0,
+		MinPlayers:      0,
+		RequireAllReady: false,
+		MaxRounds:       0,
+		AnswerTimeLimit: 0,
+		TopicCategories: []string{},
+		TopicSource:     defaultTopicSource,
+		TopicDeckIDs:    []string{},
+		CommentsEnabled: true,
+		ScoringRule:     defaultScoringRule,
+		TeamCount:       0,
+		TieRule:         defaultTieRule,
+		DiscussionTime:  defaultDiscussionTime,
+		WolfCount:       defaultWolfCount,
+		TopicDifficulty: defaultTopicDifficulty,
+	}
+}
+
+// applySettingsInput - RoomSettingsInputの指定された項目だけをbaseに上書きして検証
+// GraphQLの数値はJSON経由でfloat64として渡される
+// デッキを指定した場合は、指定したデッキがすべて存在するかも確認する
+func applySettingsInput(ctx context.Context, base RoomSettings, input map[string]interface{}) (RoomSettings, error) {
+	settings := base
+
+	if v, ok := input["maxPlayers"].(float64); ok {
+		settings.MaxPlayers = int(v)
+	}
+	if v, ok := input["minPlayers"].(float64); ok {
+		settings.MinPlayers = int(v)
+	}
+	if v, ok := input["requireAllReady"].(bool); ok {
+		settings.RequireAllReady = v
+	}
+	if v, ok := input["maxRounds"].(float64); ok {
+		settings.MaxRounds = int(v)
+	}
+	if v, ok := input["answerTimeLimit"].(float64); ok {
+		settings.AnswerTimeLimit = int(v)
+	}
+	if v, ok := input["topicCategories"].([]interface{}); ok {
+		settings.TopicCategories = []string{}
+		for _, c := range v {
+			if s, ok := c.(string); ok {
+				settings.TopicCategories = append(settings.TopicCategories, s)
+			}
+		}
+	}
+	if v, ok := input["topicSource"].(string); ok {
+		settings.TopicSource = v
+	}
+	if v, ok := input["topicDeckIds"].([]interface{}); ok {
+		settings.TopicDeckIDs = []string{}
+		for _, id := range v {
+			if s, ok := id.(string); ok && !slices.Contains(settings.TopicDeckIDs, s) {
+				settings.TopicDeckIDs = append(settings.TopicDeckIDs, s)
+			}
+		}
+	}
+	if v, ok := input["commentsEnabled"].(bool); ok {
+		settings.CommentsEnabled = v
+	}
+	if v, ok := input["scoringRule"].(string); ok {
+		settings.ScoringRule = v
+	}
+	if v, ok := input["teamCount"].(float64); ok {
+		settings.TeamCount = int(v)
+	}
+	if v, ok := input["tieRule"].(string); ok {
+		settings.TieRule = v
+	}
+	if v, ok := input["discussionTime"].(float64); ok {
+		settings.DiscussionTime = int(v)
+	}
+	if v, ok := input["wolfCount"].(float64); ok {
+		settings.WolfCount = int(v)
+	}
+	if v, ok := input["topicDifficulty"].(string); ok {
+		settings.TopicDifficulty = v
+	}
+
+	if err := validateRoomSettings(settings); err != nil {
+		return base, err
+	}
+	if _, ok := input["topicDeckIds"]; ok {
+		if err := requireTopicDecksExist(ctx, settings.TopicDeckIDs); err != nil {
+			return base, err
+		}
+	}
+	return settings, nil
+}
+
+// validateRoomSettings - 設定値の範囲を検証
+func validateRoomSettings(settings RoomSettings) error {
+	if settings.MaxPlayers != 0 && (settings.MaxPlayers < 2 || settings.MaxPlayers > maxPlayersLimit) {
+		return fmt.Errorf("最大人数は2〜%d人で指定してください（0は無制限）", maxPlayersLimit)
+	}
+	if settings.MinPlayers != 0 && (settings.MinPlayers < 2 || settings.MinPlayers > maxPlayersLimit) {
+		return fmt.Errorf("最小人数は2〜%d人で指定してください（0は制限なし）", maxPlayersLimit)
+	}
+	if settings.MaxPlayers > 0 && settings.MinPlayers > settings.MaxPlayers {
+		return fmt.Errorf("最小人数は最大人数以下で指定してください")
+	}
+	if settings.MaxRounds < 0 || settings.MaxRounds > maxRoundsLimit {
+		return fmt.Errorf("ラウンド数は1〜%dで指定してください（0は無制限）", maxRoundsLimit)
+	}
+	if settings.AnswerTimeLimit != 0 && (settings.AnswerTimeLimit < answerTimeLimitMin || settings.AnswerTimeLimit > answerTimeLimitMax) {
+		return fmt.Errorf("回答制限時間は%d〜%d秒で指定してください（0は無制限）", answerTimeLimitMin, answerTimeLimitMax)
+	}
+	for _, c := range settings.TopicCategories {
+		if !topicgen.IsCategory(c) {
+			return fmt.Errorf("不明なお題カテゴリ: %s", c)
+		}
+	}
+	if !validTopicSources[settings.TopicSource] {
+		return fmt.Errorf("不明なお題の出典: %s", settings.TopicSource)
+	}
+	if settings.TopicSource != "AI" && len(settings.TopicDeckIDs) == 0 {
+		return fmt.Errorf("お題の出典にデッキを使う場合はデッキを1つ以上選択してください")
+	}
+	if len(settings.TopicDeckIDs) > maxRoomTopicDecks {
+		return fmt.Errorf("選択できるデッキは%d個までです", maxRoomTopicDecks)
+	}
+	if !validScoringRules[settings.ScoringRule] {
+		return fmt.Errorf("不明な得点ルール: %s", settings.ScoringRule)
+	}
+	if settings.TeamCount != 0 && (settings.TeamCount < 2 || settings.TeamCount > maxTeamCount) {
+		return fmt.Errorf("チーム数は2〜%dで指定してください（0はチーム戦なし）", maxTeamCount)
+	}
+	if !validTieRules[settings.TieRule] {
+		return fmt.Errorf("不明な同数時のルール: %s", settings.TieRule)
+	}
+	if settings.ScoringRule == "MAJORITY" && settings.TeamCount > 0 {
+		return fmt.Errorf("多数派モードとチーム戦は同時に使用できません")
+	}
+	if settings.DiscussionTime != 0 && (settings.DiscussionTime < discussionTimeMin || settings.DiscussionTime > discussionTimeMax) {
+		return fmt.Errorf("議論時間は%d〜%d秒で指定してください（0は無制限）", discussionTimeMin, discussionTimeMax)
+	}
+	if settings.WolfCount < 1 || settings.WolfCount > maxWolfCount {
+		return fmt.Errorf("少数派の人数は1〜%d人で指定してください", maxWolfCount)
+	}
+	if !validTopicDifficulties[settings.TopicDifficulty] {
+		return fmt.Errorf("不明なお題の難易度: %s", settings.TopicDifficulty)
+	}
+	return nil
+}
+
+// applyAccessInput - RoomSettingsInputの公開設定・パスワードをルームに反映
+// passwordに空文字を指定するとパスワードを解除する
+func applyAccessInput(room *Room, input map[string]interface{}) error {
+	if v, ok := input["visibility"].(string); ok {
+		if !validVisibilities[v] {
+			return fmt.Errorf("不明な公開設定: %s", v)
+		}
+		room.Visibility = v
+	}
+
+	if v, ok := input["password"].(string); ok {
+		if v == "" {
+			room.PasswordHash = ""
+			room.PasswordSalt = ""
+		} else {
+			if utf8.RuneCountInString(v) > maxPasswordLength {
+				return fmt.Errorf("パスワードは%d文字以内で指定してください", maxPasswordLength)
+			}
+			salt := make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return fmt.Errorf("ソルトの生成に失敗: %w", err)
+			}
+			hash, err := hashPassword(hex.EncodeToString(salt), v)
+			if err != nil {
+				return err
+			}
+			room.PasswordSalt, room.PasswordHash = hex.EncodeToString(salt), hash
+		}
+	}
+	room.HasPassword = room.PasswordHash != ""
+
+	return nil
+}
+
+// hashPassword - ソルト付きでパスワードをハッシュ化（scrypt）
+func hashPassword(salt, password string) (string, error) {
+	key, err := scrypt.Key([]byte(password), []byte(salt), scryptN, scryptR, scryptP, scryptKeyLength)
+	if err != nil {
+		return "", fmt.Errorf("パスワードのハッシュ化に失敗: %w", err)
+	}
+	return passwordHashPrefix + hex.EncodeToString(key), nil
+}
+
+// legacyPasswordHash - 旧形式（SHA-256）のパスワードハッシュ
+// scryptに切り替える前に作成されたルーム（最長でもTTLの24時間で削除される）の検証にのみ使う
+func legacyPasswordHash(salt, password string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkRoomPassword - 参加パスワードを検証（パスワード未設定のルームは常にtrue）
+func checkRoomPassword(room *Room, password string) bool {
+	if room.PasswordHash == "" {
+		return true
+	}
+	var given string
+	if strings.HasPrefix(room.PasswordHash, passwordHashPrefix) {
+		hash, err := hashPassword(room.PasswordSalt, password)
+		if err != nil {
+			log.Printf("警告: %v", err)
+			return false
+		}
+		given = hash
+	} else {
+		given = legacyPasswordHash(room.PasswordSalt, password)
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(room.PasswordHash)) == 1
+}
+
+// answerDeadline - 設定された制限時間から回答締め切り時刻を計算（無制限の場合はnil）
+func answerDeadline(settings *RoomSettings) *string {
+	if settings == nil || settings.AnswerTimeLimit <= 0 {
+		return nil
+	}
+	deadline := time.Now().UTC().Add(time.Duration(settings.AnswerTimeLimit) * time.Second).Format(time.RFC3339)
+	return &deadline
+}
+
+// answerDeadlineUpdate - UpdateItemの式に回答締め切りの更新を追加
+// 締め切りがある場合はSET句の断片を、ない場合はREMOVE句に加える属性名を返す
+func answerDeadlineUpdate(deadline *string, names map[string]string, values map[string]types.AttributeValue) (setClause, removeClause string) {
+	names["#answerDeadline"] = "answerDeadline"
+	if deadline == nil {
+		return "", "#answerDeadline"
+	}
+	values[":answerDeadline"] = &types.AttributeValueMemberS{Value: *deadline}
+	return ", #answerDeadline = :answerDeadline", ""
+}
+
+// isPastDeadline - 回答締め切りを過ぎているか判定（猶予時間を含む）
+func isPastDeadline(deadline *string) bool {
+	if deadline == nil {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, *deadline)
+	if err != nil {
+		return false
+	}
+	return time.Now().After(t.Add(answerDeadlineGraceS * time.Second))
+}
+
+// updateRoomSettings - ルーム設定を変更（ホストのみ、WAITING中のみ）
+func updateRoomSettings(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	input, _ := args["settings"].(map[string]interface{})
+
+	log.Printf("ルーム設定変更: roomId=%s, settings=%+v", roomID, redactArgs(input))
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+
+	if room.HostID != playerID {
+		return nil, fmt.Errorf("ホストのみが設定を変更できます")
+	}
+	if room.State != "WAITING" {
+		return nil, fmt.Errorf("設定はゲーム開始前のみ変更できます")
+	}
+
+	settings, err := applySettingsInput(ctx, *room.Settings, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAccessInput(room, input); err != nil {
+		return nil, err
+	}
+
+	// 現在の参加人数より少ない最大人数は設定できない（観戦者は数えない）
+	if active := countActivePlayers(room.Players); settings.MaxPlayers > 0 && active > settings.MaxPlayers {
+		return nil, fmt.Errorf("現在の参加人数（%d人）より少ない最大人数は設定できません", active)
+	}
+
+	settingsItem, err := attributevalue.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("設定のマーシャルに失敗: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	names := map[string]string{
+		"#settings":     "settings",
+		"#visibility":   "visibility",
+		"#passwordHash": "passwordHash",
+		"#passwordSalt": "passwordSalt",
+		"#updatedAt":    "updatedAt",
+	}
+	values := map[string]types.AttributeValue{
+		":settings":   settingsItem,
+		":visibility": &types.AttributeValueMemberS{Value: room.Visibility},
+		":updatedAt":  &types.AttributeValueMemberS{Value: now},
+	}
+	expr := "SET #settings = :settings, #visibility = :visibility, #updatedAt = :updatedAt"
+	if room.PasswordHash != "" {
+		expr += ", #passwordHash = :passwordHash, #passwordSalt = :passwordSalt"
+		values[":passwordHash"] = &types.AttributeValueMemberS{Value: room.PasswordHash}
+		values[":passwordSalt"] = &types.AttributeValueMemberS{Value: room.PasswordSalt}
+	} else {
+		expr += " REMOVE #passwordHash, #passwordSalt"
+	}
+
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+		UpdateExpression:          aws.String(expr),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
+	}
+
+	// チーム数が変わった場合はチームを振り分け直す
+	if settings.TeamCount != room.Settings.TeamCount {
+		if err := rebalanceTeams(ctx, room.Players, settings.TeamCount); err != nil {
+			return nil, err
+		}
+	}
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRoomPassword(t *testing.T) {
+	hash, err := hashPassword("salt", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, passwordHashPrefix) {
+		t.Fatalf("hash = %s, want %s で始まるハッシュ", hash, passwordHashPrefix)
+	}
+
+	tests := []struct {
+		name     string
+		room     Room
+		password string
+		want     bool
+	}{
+		{"パスワードなし", Room{}, "", true},
+		{"一致", Room{PasswordSalt: "salt", PasswordHash: hash}, "pass", true},
+		{"不一致", Room{PasswordSalt: "salt", PasswordHash: hash}, "wrong", false},
+		{"ソルトが異なる", Room{PasswordSalt: "other", PasswordHash: hash}, "pass", false},
+		{"旧形式のハッシュ", Room{PasswordSalt: "salt", PasswordHash: legacyPasswordHash("salt", "pass")}, "pass", true},
+		{"旧形式のハッシュと不一致", Room{PasswordSalt: "salt", PasswordHash: legacyPasswordHash("salt", "pass")}, "wrong", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkRoomPassword(&tt.room, tt.password); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
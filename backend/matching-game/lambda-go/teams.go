@@ -0,0 +1,342 @@
+// teams.go - チーム戦（チーム分け・チームごとの判定と得点）
+// settings.teamCountが2以上の場合、各チームの回答がチーム内で一致したかを別々に判定する
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxTeamCount - チーム数の上限
+const maxTeamCount = 8
+
+// assignTeam - プレイヤーを指定したチームに割り当て（ホストのみ、WAITING中のみ）
+func assignTeam(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+	targetPlayerID := args["targetPlayerId"].(string)
+	team := int(args["team"].(float64))
+
+	log.Printf("チーム割り当て: roomId=%s, targetPlayerId=%s, team=%d", roomID, targetPlayerID, team)
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := checkTeamEditable(room, playerID); err != nil {
+		return nil, err
+	}
+	if team < 1 || team > room.Settings.TeamCount {
+		return nil, fmt.Errorf("チームは1〜%dで指定してください", room.Settings.TeamCount)
+	}
+
+	var target *Player
+	for i := range room.Players {
+		if room.Players[i].PlayerID == targetPlayerID {
+			target = &room.Players[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("プレイヤーが見つかりません")
+	}
+	if target.Role == "SPECTATOR" {
+		return nil, fmt.Errorf("観戦者はチームに所属できません")
+	}
+
+	if err := setPlayerTeam(ctx, targetPlayerID, team); err != nil {
+		return nil, err
+	}
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
+
+// balanceTeams - 観戦者以外の全プレイヤーをランダムに均等なチームへ振り分け（ホストのみ、WAITING中のみ）
+func balanceTeams(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+
+	log.Printf("チーム自動振り分け: roomId=%s", roomID)
+
+	room, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := checkTeamEditable(room, playerID); err != nil {
+		return nil, err
+	}
+
+	if err := rebalanceTeams(ctx, room.Players, room.Settings.TeamCount); err != nil {
+		return nil, err
+	}
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, fmt.Errorf("ルーム情報の取得に失敗: %w", err)
+	}
+
+	return updatedRoom, nil
+}
+
+// checkTeamEditable - チーム編成を変更できるか確認
+func checkTeamEditable(room *Room, playerID string) error {
+	if err := requireGameType(room, "MATCHING"); err != nil {
+		return err
+	}
+	if room.HostID != playerID {
+		return fmt.Errorf("ホストのみがチームを変更できます")
+	}
+	if room.Settings.TeamCount == 0 {
+		return fmt.Errorf("チーム戦が有効になっていません")
+	}
+	if room.State != "WAITING" {
+		return fmt.Errorf("チームはゲーム開始前のみ変更できます")
+	}
+	return nil
+}
+
+// rebalanceTeams - 観戦者以外のプレイヤーをシャッフルして順番にチームへ割り当て
+func rebalanceTeams(ctx context.Context, players []Player, teamCount int) error {
+	if teamCount == 0 {
+		return nil
+	}
+
+	active := []Player{}
+	for _, p := range players {
+		if p.Role != "SPECTATOR" {
+			active = append(active, p)
+		}
+	}
+	rand.Shuffle(len(active), func(i, j int) { active[i], active[j] = active[j], active[i] })
+
+	for i, p := range active {
+		team := i%teamCount + 1
+		if p.Team == team {
+			continue
+		}
+		if err := setPlayerTeam(ctx, p.PlayerID, team); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignUnassignedTeams - チーム未所属（または範囲外）のプレイヤーを人数の少ないチームへ割り当て
+// ゲーム開始時に、振り分け後に参加したプレイヤーを漏れなくチームに入れるために使用する
+func assignUnassignedTeams(ctx context.Context, players []Player, teamCount int) error {
+	if teamCount == 0 {
+		return nil
+	}
+
+	assigned := []Player{}
+	for _, p := range players {
+		if p.Role == "SPECTATOR" {
+			continue
+		}
+		if p.Team >= 1 && p.Team <= teamCount {
+			assigned = append(assigned, p)
+			continue
+		}
+		team := smallestTeam(assigned, teamCount)
+		if err := setPlayerTeam(ctx, p.PlayerID, team); err != nil {
+			return err
+		}
+		p.Team = team
+		assigned = append(assigned, p)
+	}
+	return nil
+}
+
+// smallestTeam - 所属人数が最も少ないチーム番号（同数の場合は番号の小さいチーム）
+func smallestTeam(players []Player, teamCount int) int {
+	counts := make([]int, teamCount+1)
+	for _, p := range players {
+		if p.Role != "SPECTATOR" && p.Team >= 1 && p.Team <= teamCount {
+			counts[p.Team]++
+		}
+	}
+
+	best := 1
+	for team := 2; team <= teamCount; team++ {
+		if counts[team] < counts[best] {
+			best = team
+		}
+	}
+	return best
+}
+
+// setPlayerTeam - プレイヤーの所属チームを更新
+func setPlayerTeam(ctx context.Context, playerID string, team int) error {
+	_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(playerTable),
+		Key: map[string]types.AttributeValue{
+			"playerId": &types.AttributeValueMemberS{Value: playerID},
+		},
+		UpdateExpression: aws.String("SET #team = :team"),
+		ExpressionAttributeNames: map[string]string{
+			"#team": "team",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":team": &types.AttributeValueMemberN{Value: strconv.Itoa(team)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("プレイヤーのチーム更新に失敗: %w", err)
+	}
+	return nil
+}
+
+// newTeamScores - 全チーム0点の得点表
+func newTeamScores(teamCount int) []TeamScore {
+	scores := []TeamScore{}
+	for team := 1; team <= teamCount; team++ {
+		scores = append(scores, TeamScore{Team: team, Score: 0})
+	}
+	return scores
+}
+
+// parseTeamVerdicts - judgeAnswersのteamVerdicts引数を検証して取得
+// 指定されなかったチームは不一致として扱う
+func parseTeamVerdicts(input interface{}, teamCount int) ([]TeamVerdict, error) {
+	list, ok := input.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("チーム戦ではteamVerdictsを指定してください")
+	}
+
+	matched := make(map[int]bool)
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("teamVerdictsの形式が正しくありません")
+		}
+		teamValue, ok := m["team"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("teamVerdictsにはチーム番号（team）を指定してください")
+		}
+		isMatch, ok := m["isMatch"].(bool)
+		if !ok {
+			return nil, fmt.Errorf("teamVerdictsには一致したか（isMatch）を指定してください")
+		}
+		team := int(teamValue)
+		if float64(team) != teamValue || team < 1 || team > teamCount {
+			return nil, fmt.Errorf("チームは1〜%dで指定してください", teamCount)
+		}
+		if _, dup := matched[team]; dup {
+			return nil, fmt.Errorf("チーム%dの判定が重複しています", team)
+		}
+		matched[team] = isMatch
+	}
+
+	verdicts := []TeamVerdict{}
+	for team := 1; team <= teamCount; team++ {
+		verdicts = append(verdicts, TeamVerdict{Team: team, IsMatch: matched[team]})
+	}
+	return verdicts, nil
+}
+
+// applyTeamVerdicts - チームごとの判定結果を得点に反映
+// 同じラウンドで判定をやり直した場合は前回の判定分を差し引く
+func applyTeamVerdicts(room *Room, verdicts []TeamVerdict) []TeamScore {
+	scores := make(map[int]int)
+	for _, s := range room.TeamScores {
+		scores[s.Team] = s.Score
+	}
+
+	if room.Settings.ScoringRule == "ALL_MATCH" {
+		for _, v := range room.LastTeamVerdicts {
+			if v.IsMatch {
+				scores[v.Team]--
+			}
+		}
+		for _, v := range verdicts {
+			if v.IsMatch {
+				scores[v.Team]++
+			}
+		}
+	}
+
+	result := []TeamScore{}
+	for team := 1; team <= room.Settings.TeamCount; team++ {
+		result = append(result, TeamScore{Team: team, Score: scores[team]})
+	}
+	return result
+}
+
+// buildTeamResult - チーム戦の判定結果と判定後の得点を作成（全チームが一致した場合のみ全体として一致とみなす）
+func buildTeamResult(room *Room, args map[string]interface{}) (*JudgeResult, error) {
+	verdicts, err := parseTeamVerdicts(args["teamVerdicts"], room.Settings.TeamCount)
+	if err != nil {
+		return nil, err
+	}
+
+	isMatch := true
+	for _, v := range verdicts {
+		if !v.IsMatch {
+			isMatch = false
+		}
+	}
+
+	return &JudgeResult{
+		RoomID:       room.RoomID,
+		IsMatch:      isMatch,
+		TeamVerdicts: verdicts,
+		TeamScores:   applyTeamVerdicts(room, verdicts),
+	}, nil
+}
+
+// saveTeamResult - チーム戦の判定結果と得点を保存
+func saveTeamResult(ctx context.Context, svc *GameServices, room *Room, result *JudgeResult) error {
+	verdictsItem, err := attributevalue.Marshal(result.TeamVerdicts)
+	if err != nil {
+		return fmt.Errorf("判定結果のマーシャルに失敗: %w", err)
+	}
+	scoresItem, err := attributevalue.Marshal(result.TeamScores)
+	if err != nil {
+		return fmt.Errorf("得点のマーシャルに失敗: %w", err)
+	}
+
+	result.JudgedAt = time.Now().UTC().Format(time.RFC3339)
+	err = svc.UpdateRoom(ctx, room.RoomID, RoomUpdate{
+		Expression: "SET #lastJudgeResult = :lastJudgeResult, #lastTeamVerdicts = :lastTeamVerdicts, #teamScores = :teamScores, #updatedAt = :updatedAt",
+		Names: map[string]string{
+			"#lastJudgeResult":  "lastJudgeResult",
+			"#lastTeamVerdicts": "lastTeamVerdicts",
+			"#teamScores":       "teamScores",
+			"#updatedAt":        "updatedAt",
+		},
+		Values: map[string]types.AttributeValue{
+			":lastJudgeResult":  &types.AttributeValueMemberBOOL{Value: result.IsMatch},
+			":lastTeamVerdicts": verdictsItem,
+			":teamScores":       scoresItem,
+			":updatedAt":        &types.AttributeValueMemberS{Value: result.JudgedAt},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("ルームの更新に失敗: %w", err)
+	}
+
+	log.Printf("チーム判定完了: roomId=%s, verdicts=%+v, scores=%+v", room.RoomID, result.TeamVerdicts, result.TeamScores)
+	return nil
+}
@@ -0,0 +1,459 @@
+// topicbank.go - 全ルームで共有するお題バンク
+// 生成したお題はバンクに蓄積し、各ルームはバンクからお題を取り出す（ルームごとにOpenAI APIを呼ばない）
+// プレイヤーが他のルームで見たお題は端末（Cognito Identity）ごとの履歴に記録し、取り出し時に除外する
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"mitsu-game-lambda/topicgen"
+)
+
+const (
+	roomTopicPoolSize         = 130              // バンクから1ルームに取り出すお題の数
+	topicBankCacheDuration    = 5 * time.Minute  // バンクの内容をLambdaのメモリに保持する時間
+	topicBankRefillThreshold  = 50               // カテゴリごとのお題がこの数を下回ったら補充する
+	topicHistoryRetentionDays = 30               // 端末ごとの閲覧履歴を保持する日数
+	topicHistoryMaxTopics     = 500              // 閲覧履歴の1世代に記録するお題の数（超えたら前の世代に移して記録し直す）
+	topicBankCategoryIndex    = "category-index" // お題バンクのカテゴリのGSI
+	batchWriteSize            = 25               // BatchWriteItemの1回あたりの上限
+	batchGetSize              = 100              // BatchGetItemの1回あたりの上限
+	maxUnprocessedRetries     = 5                // BatchGetItem・BatchWriteItemで処理されなかった項目を送り直す回数
+)
+
+// topicBankCache - カテゴリごとのバンクの内容のキャッシュ（ウォームスタート間で共有）
+// muはキャッシュの読み書きの間だけ保持し、DynamoDBのクエリ中は保持しない
+var topicBankCache struct {
+	mu         sync.Mutex
+	categories map[string]topicBankEntry
+	generation int // キャッシュを破棄するたびに加算（破棄前に始めたクエリの結果を書き込まないため）
+}
+
+// topicBankEntry - 1カテゴリ分のバンクの内容
+type topicBankEntry struct {
+	topics   []Topic
+	loadedAt time.Time
+}
+
+// TopicRefillReport - バンクの補充結果
+type TopicRefillReport struct {
+	CategoryCounts map[string]int `json:"categoryCounts"` // 補充前のカテゴリごとのお題数
+	RefilledFor    []string       `json:"refilledFor"`    // 補充したカテゴリ
+	Added          int            `json:"added"`          // 追加したお題数
+}
+
+// loadTopicBank - 指定したカテゴリ（空の場合は全カテゴリ）のバンクのお題を取得
+// カテゴリのGSIをカテゴリごとにクエリし、キャッシュが有効な間はDynamoDBを読まない
+func loadTopicBank(ctx context.Context, categories []string) ([]Topic, error) {
+	if len(categories) == 0 {
+		for _, c := range topicgen.Categories {
+			categories = append(categories, c.Name)
+		}
+	}
+
+	// キャッシュが有効なカテゴリと、クエリが必要なカテゴリに分ける
+	topicBankCache.mu.Lock()
+	generation := topicBankCache.generation
+	cached := make(map[string][]Topic)
+	var stale []string
+	for _, category := range categories {
+		entry, ok := topicBankCache.categories[category]
+		if ok && time.Since(entry.loadedAt) < topicBankCacheDuration {
+			cached[category] = entry.topics
+		} else {
+			stale = append(stale, category)
+		}
+	}
+	topicBankCache.mu.Unlock()
+
+	for _, category := range stale {
+		loaded, err := queryTopicBank(ctx, category)
+		if err != nil {
+			return nil, err
+		}
+		cached[category] = loaded
+
+		topicBankCache.mu.Lock()
+		if topicBankCache.generation == generation {
+			if topicBankCache.categories == nil {
+				topicBankCache.categories = make(map[string]topicBankEntry)
+			}
+			topicBankCache.categories[category] = topicBankEntry{topics: loaded, loadedAt: time.Now()}
+		}
+		topicBankCache.mu.Unlock()
+	}
+
+	topics := []Topic{}
+	for _, category := range categories {
+		topics = append(topics, cached[category]...)
+	}
+	return topics, nil
+}
+
+// queryTopicBank - 1カテゴリのバンクのお題をGSIから取得（ページネーション対応）
+func queryTopicBank(ctx context.Context, category string) ([]Topic, error) {
+	topics := []Topic{}
+
+	paginator := dynamodb.NewQueryPaginator(ddbClient, &dynamodb.QueryInput{
+		TableName:              aws.String(topicBankTable),
+		IndexName:              aws.String(topicBankCategoryIndex),
+		KeyConditionExpression: aws.String("#category = :category"),
+		ExpressionAttributeNames: map[string]string{
+			"#category": "category",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":category": &types.AttributeValueMemberS{Value: category},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("お題バンクのクエリに失敗: %w", err)
+		}
+		for _, item := range page.Items {
+			var t Topic
+			if err := attributevalue.UnmarshalMap(item, &t); err != nil {
+				log.Printf("警告: お題バンクの項目のアンマーシャルに失敗: %v", err)
+				continue
+			}
+			topics = append(topics, t)
+		}
+	}
+
+	return topics, nil
+}
+
+// addToTopicBank - お題をバンクに追加（同じ文のお題は上書き、処理されなかった項目は待ってから送り直す）
+func addToTopicBank(ctx context.Context, topics []Topic) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	for start := 0; start < len(topics); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(topics) {
+			end = len(topics)
+		}
+
+		var requests []types.WriteRequest
+		for _, t := range topics[start:end] {
+			item, err := attributevalue.MarshalMap(t)
+			if err != nil {
+				return fmt.Errorf("お題のマーシャルに失敗: %w", err)
+			}
+			item["createdAt"] = &types.AttributeValueMemberS{Value: now}
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		}
+
+		if err := batchWriteItems(ctx, topicBankTable, requests); err != nil {
+			return fmt.Errorf("お題バンクへの追加に失敗: %w", err)
+		}
+	}
+
+	// 次の取り出しで追加分が見えるようにキャッシュを破棄
+	topicBankCache.mu.Lock()
+	topicBankCache.categories = nil
+	topicBankCache.generation++
+	topicBankCache.mu.Unlock()
+	return nil
+}
+
+// batchWriteItems - 書き込みをまとめて送信（batchWriteSize件以内、処理されなかった項目は待ってから送り直す）
+func batchWriteItems(ctx context.Context, table string, requests []types.WriteRequest) error {
+	pending := map[string][]types.WriteRequest{table: requests}
+	for attempt := 0; len(pending[table]) > 0; attempt++ {
+		if attempt == maxUnprocessedRetries {
+			return fmt.Errorf("%sに書き込めなかった項目が%d個あります", table, len(pending[table]))
+		}
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(50<<attempt) * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		result, err := ddbClient.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return fmt.Errorf("%sの一括書き込みに失敗: %w", table, err)
+		}
+		pending = result.UnprocessedItems
+	}
+	return nil
+}
+
+// drawTopics - ルームのお題プール用にバンク・デッキからお題を取り出す
+// 使用済みのお題・参加者が他のルームで見たお題・評価の低いお題・指定外のカテゴリを除き、ランダムな順で返す
+// 難易度を固定したルームでは、区分が合うお題を優先して取り出す
+// お題の出典がDECKの場合は選択したデッキのみ、MIXEDの場合はデッキとバンクのお題を交互に並べる
+// 取り出せるお題がない場合はその場で生成し、生成したお題はバンクにも追加する（生成に失敗した場合は組み込みのお題を使う）
+func drawTopics(ctx context.Context, room *Room, usedTopics []string) ([]Topic, error) {
+	categories := room.Settings.TopicCategories
+	source := room.Settings.TopicSource
+
+	var bank []Topic
+	if source != "DECK" {
+		var err error
+		bank, err = loadTopicBank(ctx, categories)
+		if err != nil {
+			// バンクが読めなくてもゲームは止めず、ルーム単位の生成で続行する
+			log.Printf("警告: %v", err)
+			bank = nil
+		}
+	}
+
+	var deck []Topic
+	if source != "AI" {
+		var err error
+		deck, err = loadDeckTopics(ctx, room.Settings.TopicDeckIDs)
+		if err != nil {
+			if source == "DECK" {
+				return nil, err
+			}
+			// MIXEDではデッキが読めなくてもバンクのお題で続行する
+			log.Printf("警告: %v", err)
+		}
+	}
+
+	excluded := make(map[string]bool)
+	for _, t := range usedTopics {
+		excluded[t] = true
+	}
+	seen, err := loadSeenTopics(ctx, room.Players)
+	if err != nil {
+		log.Printf("警告: お題の閲覧履歴の取得に失敗: %v", err)
+	}
+	for t := range seen {
+		excluded[t] = true
+	}
+	// 評価の低いお題は候補（デッキ・バンク・組み込みのお題）の記録だけを読んで判断する
+	candidates := append(append(topicTexts(deck), topicTexts(bank)...), topicTexts(fallbackTopics)...)
+	lowQuality := loadLowQualityTopics(ctx, candidates)
+	for t := range lowQuality {
+		excluded[t] = true
+	}
+
+	fromDeck := []Topic{}
+	for _, t := range deck {
+		if !excluded[t.Text] {
+			fromDeck = append(fromDeck, t)
+			excluded[t.Text] = true
+		}
+	}
+	fromBank := []Topic{}
+	for _, t := range bank {
+		if !excluded[t.Text] {
+			fromBank = append(fromBank, t)
+		}
+	}
+
+	if len(fromDeck) > 0 || len(fromBank) > 0 {
+		available := interleaveTopics(preferDifficulty(ctx, room.Settings, fromDeck), preferDifficulty(ctx, room.Settings, fromBank))
+		if len(available) > roomTopicPoolSize {
+			available = available[:roomTopicPoolSize]
+		}
+		log.Printf("お題を%d個取り出しました（デッキ: %d個、バンク: %d個）", len(available), len(deck), len(bank))
+		return available, nil
+	}
+
+	// デッキのみを出典とするルームでは生成しない
+	if source == "DECK" {
+		return nil, fmt.Errorf("選択したデッキに出題できるお題が残っていません")
+	}
+
+	// 評価の低いお題は使用済みとして扱い、生成・組み込みのお題からも除外する
+	log.Println("お題バンクに使えるお題がないため生成中...")
+	avoid := append([]string{}, usedTopics...)
+	for t := range lowQuality {
+		avoid = append(avoid, t)
+	}
+	generated, err := generateTopics(ctx, avoid, categories)
+	if err != nil {
+		return fallbackTopicsFor(avoid, categories, err)
+	}
+	if err := addToTopicBank(ctx, generated); err != nil {
+		log.Printf("警告: %v", err)
+	}
+	return generated, nil
+}
+
+// loadSeenTopics - 参加者の端末が過去に見たお題の集合（閲覧履歴の現在と前の世代）
+func loadSeenTopics(ctx context.Context, players []Player) (map[string]bool, error) {
+	seen := make(map[string]bool)
+
+	var keys []map[string]types.AttributeValue
+	added := make(map[string]bool)
+	for _, p := range players {
+		if p.IdentityID == "" || added[p.IdentityID] {
+			continue
+		}
+		added[p.IdentityID] = true
+		keys = append(keys, map[string]types.AttributeValue{
+			"identityId": &types.AttributeValueMemberS{Value: p.IdentityID},
+		})
+	}
+
+	items, err := batchGetItems(ctx, topicHistoryTable, keys, types.KeysAndAttributes{
+		ProjectionExpression: aws.String("topics, previousTopics"),
+	})
+	if err != nil {
+		return seen, err
+	}
+	for _, item := range items {
+		for _, attr := range []string{"topics", "previousTopics"} {
+			if set, ok := item[attr].(*types.AttributeValueMemberSS); ok {
+				for _, t := range set.Value {
+					seen[t] = true
+				}
+			}
+		}
+	}
+
+	return seen, nil
+}
+
+// recordSeenTopic - 出題したお題を参加者の端末ごとの閲覧履歴に記録（失敗しても進行は止めない）
+// 項目の大きさの上限（400KB）を超えないよう、1世代がtopicHistoryMaxTopics個に達したら前の世代に移して記録し直す
+func recordSeenTopic(ctx context.Context, players []Player, topic string) {
+	ttl := time.Now().AddDate(0, 0, topicHistoryRetentionDays).Unix()
+	recorded := make(map[string]bool)
+	for _, p := range players {
+		if p.IdentityID == "" || recorded[p.IdentityID] {
+			continue
+		}
+		recorded[p.IdentityID] = true
+
+		names := map[string]string{
+			"#topics": "topics",
+			"#ttl":    "ttl",
+		}
+		values := map[string]types.AttributeValue{
+			":topic": &types.AttributeValueMemberSS{Value: []string{topic}},
+			":text":  &types.AttributeValueMemberS{Value: topic},
+			":max":   &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", topicHistoryMaxTopics)},
+			":ttl":   &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", ttl)},
+		}
+		key := map[string]types.AttributeValue{
+			"identityId": &types.AttributeValueMemberS{Value: p.IdentityID},
+		}
+
+		_, err := ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(topicHistoryTable),
+			Key:                       key,
+			UpdateExpression:          aws.String("ADD #topics :topic SET #ttl = :ttl"),
+			ConditionExpression:       aws.String("attribute_not_exists(#topics) OR size(#topics) < :max OR contains(#topics, :text)"),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		})
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			// 現在の世代が上限に達したため、前の世代に移して新しい世代に記録する
+			names["#previousTopics"] = "previousTopics"
+			delete(values, ":text")
+			_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:                 aws.String(topicHistoryTable),
+				Key:                       key,
+				UpdateExpression:          aws.String("SET #previousTopics = #topics, #topics = :topic, #ttl = :ttl"),
+				ConditionExpression:       aws.String("size(#topics) >= :max"),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+			})
+			if errors.As(err, &condErr) {
+				// 別のルームが先に世代を移した
+				continue
+			}
+		}
+		if err != nil {
+			log.Printf("警告: お題の閲覧履歴の記録に失敗 %s: %v", p.PlayerID, err)
+		}
+	}
+}
+
+// batchGetItems - キーの項目をまとめて取得（batchGetSize件ずつ、処理されなかったキーは待ってから再取得する）
+// requestには射影等を指定する（Keysは無視する）
+func batchGetItems(ctx context.Context, table string, keys []map[string]types.AttributeValue, request types.KeysAndAttributes) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+
+	for start := 0; start < len(keys); start += batchGetSize {
+		end := start + batchGetSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		request.Keys = keys[start:end]
+		pending := map[string]types.KeysAndAttributes{table: request}
+		for attempt := 0; len(pending[table].Keys) > 0; attempt++ {
+			if attempt == maxUnprocessedRetries {
+				return items, fmt.Errorf("%sから取得できなかった項目が%d個あります", table, len(pending[table].Keys))
+			}
+			if attempt > 0 {
+				select {
+				case <-time.After(time.Duration(50<<attempt) * time.Millisecond):
+				case <-ctx.Done():
+					return items, ctx.Err()
+				}
+			}
+
+			result, err := ddbClient.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return items, fmt.Errorf("%sの一括取得に失敗: %w", table, err)
+			}
+			items = append(items, result.Responses[table]...)
+			pending = result.UnprocessedKeys
+		}
+	}
+
+	return items, nil
+}
+
+// scheduledTopicRefill - EventBridgeの定期実行から呼ばれるバンクの補充処理
+// AppSyncスキーマには存在しないフィールドのため、Lambdaの直接呼び出しでのみ実行される
+// お題が閾値を下回ったカテゴリのみを指定して生成する（全カテゴリ十分な場合はOpenAI APIを呼ばない）
+func scheduledTopicRefill(ctx context.Context) (*TopicRefillReport, error) {
+	bank, err := loadTopicBank(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TopicRefillReport{
+		CategoryCounts: make(map[string]int),
+		RefilledFor:    []string{},
+	}
+	// 評価の低いお題は出題されないため、残りの数に含めない
+	lowQuality := loadLowQualityTopics(ctx, topicTexts(bank))
+	for _, t := range bank {
+		if !lowQuality[t.Text] {
+			report.CategoryCounts[t.Category]++
+		}
+	}
+	for _, c := range topicgen.Categories {
+		if report.CategoryCounts[c.Name] < topicBankRefillThreshold {
+			report.RefilledFor = append(report.RefilledFor, c.Name)
+		}
+	}
+	if len(report.RefilledFor) == 0 {
+		log.Printf("お題バンクは十分です: %v", report.CategoryCounts)
+		return report, nil
+	}
+
+	// 組み込みのお題はバンクに入れず、生成に失敗した場合はそのままエラーにする
+	// 生成済みのお題（評価の低いお題を含む）を避けるため、それらを使用済みとして渡す
+	generated, err := generateTopics(ctx, topicTexts(bank), report.RefilledFor)
+	if err != nil {
+		return nil, fmt.Errorf("お題バンクの補充に失敗: %w", err)
+	}
+	if err := addToTopicBank(ctx, generated); err != nil {
+		return nil, err
+	}
+	report.Added = len(generated)
+
+	log.Printf("お題バンクを補充しました: %+v", *report)
+	return report, nil
+}
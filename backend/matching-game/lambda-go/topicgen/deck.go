@@ -0,0 +1,256 @@
+// deck.go - お題デッキのファイル形式（JSON・CSV）の読み書きと検証
+// Lambdaのインポート・エクスポート（importTopicDeck・exportTopicDeck）とcmd/topicgenの出力で同じ形式を使う
+package topicgen
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultDeckLanguage = "ja" // 言語を省略したお題の言語
+	DeckListSeparator   = "|"  // CSVのtags・exampleAnswers列で値を区切る文字
+
+	maxDeckCategoryLength = 20 // カテゴリの最大文字数
+	maxDeckTagsPerTopic   = 10 // 1お題あたりのタグの上限
+	maxDeckTagLength      = 20 // タグの最大文字数
+)
+
+// DeckCSVHeader - CSVの列（インポート時は列の順序は問わず、topic以外は省略可能）
+var DeckCSVHeader = []string{"topic", "category", "difficulty", "tags", "language", "exampleAnswers"}
+
+// Difficulties - お題の難易度の区分と代表値（デッキの難易度の指定・ルーム設定の難易度・難易度の推定で共通）
+// 代表値は、記録の少ないデッキのお題の推定難易度と、区分に合うお題がない場合に最も近いお題を選ぶ基準に使う
+var Difficulties = []struct {
+	Level  string
+	Center float64
+}{
+	{"EASY", 0.2},
+	{"MEDIUM", 0.5},
+	{"HARD", 0.8},
+}
+
+// DifficultyCenter - 難易度の区分の代表値（定義済みの区分でない場合はfalse）
+func DifficultyCenter(level string) (float64, bool) {
+	for _, d := range Difficulties {
+		if d.Level == level {
+			return d.Center, true
+		}
+	}
+	return 0, false
+}
+
+// difficultyLevels - 難易度の区分の一覧（エラーメッセージ用）
+func difficultyLevels() string {
+	levels := make([]string, 0, len(Difficulties))
+	for _, d := range Difficulties {
+		levels = append(levels, d.Level)
+	}
+	return strings.Join(levels, "・")
+}
+
+// deckLanguagePattern - 言語の形式（BCP 47の言語タグ、例: ja, en, pt-BR）
+var deckLanguagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// DeckTopic - デッキのお題（インポート・エクスポートの1行）
+type DeckTopic struct {
+	Topic          string   `json:"topic" dynamodbav:"text"`                                        // お題の文
+	Category       string   `json:"category" dynamodbav:"category,omitempty"`                       // カテゴリ（自由入力、空は未分類）
+	Difficulty     string   `json:"difficulty" dynamodbav:"difficulty,omitempty"`                   // 難易度（EASY/MEDIUM/HARD、空は未指定）
+	Tags           []string `json:"tags" dynamodbav:"tags,omitempty"`                               // タグ
+	Language       string   `json:"language" dynamodbav:"language"`                                 // 言語（例: ja）
+	ExampleAnswers []string `json:"exampleAnswers,omitempty" dynamodbav:"exampleAnswers,omitempty"` // 想定回答（任意、1〜3個）
+}
+
+// DeckFile - JSONでのインポート・エクスポートの形式
+type DeckFile struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Topics      []DeckTopic `json:"topics"`
+}
+
+// ParseDeckJSON - JSONのデッキを読み込む（{"name", "description", "topics": [...]} またはお題の配列）
+func ParseDeckJSON(content string) (*DeckFile, error) {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "[") {
+		var topics []DeckTopic
+		if err := json.Unmarshal([]byte(trimmed), &topics); err != nil {
+			return nil, fmt.Errorf("JSONの読み込みに失敗: %w", err)
+		}
+		return &DeckFile{Topics: topics}, nil
+	}
+
+	var file DeckFile
+	if err := json.Unmarshal([]byte(trimmed), &file); err != nil {
+		return nil, fmt.Errorf("JSONの読み込みに失敗: %w", err)
+	}
+	return &file, nil
+}
+
+// ParseDeckCSV - CSVのデッキを読み込む（1行目は列名、tags・exampleAnswers列はDeckListSeparatorで区切る）
+func ParseDeckCSV(content string) ([]DeckTopic, error) {
+	// Excel等で保存したCSVの先頭のBOMは取り除く
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff")))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSVの列名の読み込みに失敗: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := columns["topic"]; !ok {
+		return nil, fmt.Errorf("CSVにtopic列がありません（列: %s）", strings.Join(DeckCSVHeader, ","))
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var topics []DeckTopic
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSVの読み込みに失敗: %w", err)
+		}
+		t := DeckTopic{
+			Topic:      field(record, "topic"),
+			Category:   field(record, "category"),
+			Difficulty: field(record, "difficulty"),
+			Language:   field(record, "language"),
+			Tags:       []string{},
+		}
+		if tags := field(record, "tags"); tags != "" {
+			t.Tags = strings.Split(tags, DeckListSeparator)
+		}
+		if answers := field(record, "exampleanswers"); answers != "" {
+			t.ExampleAnswers = strings.Split(answers, DeckListSeparator)
+		}
+		topics = append(topics, t)
+	}
+	return topics, nil
+}
+
+// FormatDeckCSV - デッキのお題をCSVに出力
+func FormatDeckCSV(topics []DeckTopic) (string, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(DeckCSVHeader); err != nil {
+		return "", fmt.Errorf("CSVの出力に失敗: %w", err)
+	}
+	for _, t := range topics {
+		record := []string{t.Topic, t.Category, t.Difficulty, strings.Join(t.Tags, DeckListSeparator), t.Language, strings.Join(t.ExampleAnswers, DeckListSeparator)}
+		if err := writer.Write(record); err != nil {
+			return "", fmt.Errorf("CSVの出力に失敗: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", fmt.Errorf("CSVの出力に失敗: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ValidateDeckTopics - 読み込んだ行を検証して整形（不正な行・重複した行は除き、理由を返す）
+// 行番号はお題の1件目を1とする（CSVの列名の行は数えない）
+func ValidateDeckTopics(rows []DeckTopic) ([]DeckTopic, []string) {
+	topics := []DeckTopic{}
+	skipped := []string{}
+	seen := make(map[string]bool)
+	for i, row := range rows {
+		t, err := ValidateDeckTopic(row)
+		if err == nil && seen[t.Topic] {
+			err = fmt.Errorf("重複したお題です")
+		}
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%d行目: %v", i+1, err))
+			continue
+		}
+		seen[t.Topic] = true
+		topics = append(topics, t)
+	}
+	return topics, skipped
+}
+
+// ValidateDeckTopic - デッキのお題1件を検証して整形
+// 生成したお題と同じく文の長さ・改行を確認するが、質問の形式・想定回答は求めない（想定回答がある場合は重複を除いて3個まで）
+func ValidateDeckTopic(t DeckTopic) (DeckTopic, error) {
+	t.Topic = strings.TrimSpace(t.Topic)
+	t.Category = strings.TrimSpace(t.Category)
+	t.Difficulty = strings.ToUpper(strings.TrimSpace(t.Difficulty))
+	t.Language = strings.TrimSpace(t.Language)
+
+	if t.Topic == "" {
+		return t, fmt.Errorf("お題が空です")
+	}
+	if strings.ContainsAny(t.Topic, "\r\n") {
+		return t, fmt.Errorf("お題に改行は使えません")
+	}
+	if utf8.RuneCountInString(t.Topic) > MaxTopicLength {
+		return t, fmt.Errorf("お題は%d文字以内にしてください", MaxTopicLength)
+	}
+	if utf8.RuneCountInString(t.Category) > maxDeckCategoryLength {
+		return t, fmt.Errorf("カテゴリは%d文字以内にしてください", maxDeckCategoryLength)
+	}
+	if t.Difficulty != "" {
+		if _, ok := DifficultyCenter(t.Difficulty); !ok {
+			return t, fmt.Errorf("不明な難易度: %s（%sのいずれか）", t.Difficulty, difficultyLevels())
+		}
+	}
+	if t.Language == "" {
+		t.Language = DefaultDeckLanguage
+	}
+	// 言語の部分（先頭）は小文字に揃える（EN-us → en-us）
+	if lang, region, ok := strings.Cut(t.Language, "-"); ok {
+		t.Language = strings.ToLower(lang) + "-" + region
+	} else {
+		t.Language = strings.ToLower(t.Language)
+	}
+	if !deckLanguagePattern.MatchString(t.Language) {
+		return t, fmt.Errorf("不明な言語: %s（例: ja, en）", t.Language)
+	}
+
+	tags := []string{}
+	for _, tag := range t.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxDeckTagLength || strings.Contains(tag, DeckListSeparator) {
+			return t, fmt.Errorf("タグは%d文字以内で「%s」を含まないようにしてください: %s", maxDeckTagLength, DeckListSeparator, tag)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxDeckTagsPerTopic {
+		return t, fmt.Errorf("タグは1お題あたり%d個までです", maxDeckTagsPerTopic)
+	}
+	t.Tags = tags
+
+	answers := []string{}
+	for _, a := range t.ExampleAnswers {
+		a = strings.TrimSpace(a)
+		if a != "" && !slices.Contains(answers, a) {
+			answers = append(answers, a)
+		}
+	}
+	if len(answers) > MaxExampleAnswers {
+		return t, fmt.Errorf("想定回答は%d個までです", MaxExampleAnswers)
+	}
+	t.ExampleAnswers = answers
+	return t, nil
+}
@@ -0,0 +1,97 @@
+package topicgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeckCSVRoundTrip(t *testing.T) {
+	topics := []DeckTopic{
+		{Topic: "社内で一番人気のランチは？", Category: "社内", Difficulty: "EASY", Tags: []string{"ランチ", "社内"}, Language: "ja", ExampleAnswers: []string{"カレー", "そば"}},
+		{Topic: "Favorite fruit, in one word?", Category: "", Difficulty: "", Tags: []string{}, Language: "en", ExampleAnswers: nil},
+		{Topic: `"引用符"を含むお題は？`, Category: "その他", Difficulty: "HARD", Tags: []string{}, Language: "pt-BR", ExampleAnswers: []string{"a"}},
+	}
+
+	content, err := FormatDeckCSV(topics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseDeckCSV(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, topics) {
+		t.Errorf("読み直した結果が一致しない\n got: %+v\nwant: %+v", parsed, topics)
+	}
+}
+
+func TestParseDeckCSV(t *testing.T) {
+	// 列の順序は問わず、BOM・列名の大文字小文字・省略した列を受け付ける
+	content := "\ufeffCategory,Topic\n食べ物,朝ごはんといえば？\n"
+	parsed, err := ParseDeckCSV(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DeckTopic{{Topic: "朝ごはんといえば？", Category: "食べ物", Tags: []string{}}}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("got %+v, want %+v", parsed, want)
+	}
+
+	if _, err := ParseDeckCSV("category,tags\n食べ物,a\n"); err == nil {
+		t.Error("topic列がないCSVがエラーにならない")
+	}
+}
+
+func TestValidateDeckTopic(t *testing.T) {
+	got, err := ValidateDeckTopic(DeckTopic{
+		Topic:          "  朝ごはんといえば ",
+		Category:       " 食べ物 ",
+		Difficulty:     " easy ",
+		Tags:           []string{" 朝 ", "", "朝"},
+		Language:       "PT-BR",
+		ExampleAnswers: []string{" パン ", "パン", "ごはん"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeckTopic{
+		Topic:          "朝ごはんといえば",
+		Category:       "食べ物",
+		Difficulty:     "EASY",
+		Tags:           []string{"朝"},
+		Language:       "pt-BR",
+		ExampleAnswers: []string{"パン", "ごはん"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got, err := ValidateDeckTopic(DeckTopic{Topic: "朝ごはんといえば？"}); err != nil || got.Language != DefaultDeckLanguage {
+		t.Errorf("言語の省略: Language = %q, err = %v", got.Language, err)
+	}
+
+	tests := []struct {
+		name    string
+		topic   DeckTopic
+		wantErr string
+	}{
+		{"空のお題", DeckTopic{Topic: " "}, "お題が空です"},
+		{"改行を含む", DeckTopic{Topic: "朝\r\nごはん"}, "改行"},
+		{"長すぎる", DeckTopic{Topic: strings.Repeat("あ", MaxTopicLength+1)}, "文字以内"},
+		{"カテゴリが長すぎる", DeckTopic{Topic: "朝ごはん", Category: strings.Repeat("あ", maxDeckCategoryLength+1)}, "カテゴリ"},
+		{"不明な難易度", DeckTopic{Topic: "朝ごはん", Difficulty: "EXTREME"}, "不明な難易度"},
+		{"不明な言語", DeckTopic{Topic: "朝ごはん", Language: "japanese"}, "不明な言語"},
+		{"区切り文字を含むタグ", DeckTopic{Topic: "朝ごはん", Tags: []string{"a" + DeckListSeparator + "b"}}, "タグ"},
+		{"タグが多すぎる", DeckTopic{Topic: "朝ごはん", Tags: strings.Split("a,b,c,d,e,f,g,h,i,j,k", ",")}, "タグは1お題あたり"},
+		{"想定回答が多すぎる", DeckTopic{Topic: "朝ごはん", ExampleAnswers: []string{"a", "b", "c", "d"}}, "想定回答"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateDeckTopic(tt.topic)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q を含むエラー", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+// Package topicgen - お題の生成・検証のルール（Lambdaとcmd/topicgenで共通）
+// カテゴリと配分、生成プロンプト、構造化出力のスキーマ、生成されたお題の検証、デッキファイルの形式を定義する
+package topicgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxTopicLength    = 60  // お題の最大文字数
+	MaxExampleAnswers = 3   // 想定回答の最大数
+	DefaultTopicCount = 130 // 全カテゴリを既定の配分で生成する場合のお題の数（Categoriesの配分の合計）
+
+	maxAvoidTopics = 100 // プロンプトに含める使用済みお題の数（最新のものから）
+)
+
+// Categories - お題のカテゴリとDefaultTopicCount問中の配分（プロンプトとルーム設定で共通）
+var Categories = []struct {
+	Name     string // カテゴリ名
+	Count    int    // 130問中の出題数
+	Examples string // プロンプトに含める例
+}{
+	{"食べ物・飲み物", 22, "コンビニ、給食、お祭り、季節の食べ物、お菓子など"},
+	{"場所・観光地", 13, "修学旅行、観光名所、都道府県の名物など"},
+	{"キャラクター・アニメ", 18, "国民的アニメ、キャラクターの特徴など"},
+	{"学校・行事", 13, "運動会、夏休み、卒業式、授業、部活など"},
+	{"動物・生き物", 13, "ペット、動物園、虫、水族館など"},
+	{"色・形・特徴", 13, "「赤い〜」「丸い〜」「甘い〜」など"},
+	{"お店・チェーン", 13, "コンビニ、ファストフード、100均など"},
+	{"乗り物・交通", 8, "電車、新幹線、飛行機など"},
+	{"スポーツ・遊び", 8, "野球、サッカー、ゲーム、カードなど"},
+	{"その他", 9, "芸能人、音楽、映画など"},
+}
+
+// Topic - 生成されたお題（構造化出力の1要素）
+type Topic struct {
+	Text           string   `json:"topic"`          // お題の文（「〜といえば？」）
+	Category       string   `json:"category"`       // カテゴリ（Categoriesのいずれか）
+	ExampleAnswers []string `json:"exampleAnswers"` // 想定される回答の例（1〜3個）
+}
+
+// IsCategory - 定義済みのカテゴリ名か判定
+func IsCategory(name string) bool {
+	for _, c := range Categories {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Quota - 1回の生成で出題させるカテゴリとお題の数
+type Quota struct {
+	Category string // カテゴリ名
+	Count    int    // お題の数
+}
+
+// Quotas - 生成するお題のカテゴリごとの配分
+type Quotas []Quota
+
+// NewQuotas - total個のお題の配分を作成（0個になったカテゴリは含めない）
+// categoriesが空の場合は全カテゴリをCategoriesの配分の比率で、指定がある場合はそのカテゴリに均等に割り当てる（端数は先頭のカテゴリから）
+func NewQuotas(categories []string, total int) Quotas {
+	var names []string
+	var weights []int
+	sum := 0
+	for _, c := range Categories {
+		weight := c.Count
+		if len(categories) > 0 {
+			if !slices.Contains(categories, c.Name) {
+				continue
+			}
+			weight = 1
+		}
+		names = append(names, c.Name)
+		weights = append(weights, weight)
+		sum += weight
+	}
+	if sum == 0 || total <= 0 {
+		return nil
+	}
+
+	counts := make([]int, len(names))
+	assigned := 0
+	for i, w := range weights {
+		counts[i] = total * w / sum
+		assigned += counts[i]
+	}
+	for i := 0; assigned < total; i = (i + 1) % len(counts) {
+		counts[i]++
+		assigned++
+	}
+
+	var quotas Quotas
+	for i, name := range names {
+		if counts[i] > 0 {
+			quotas = append(quotas, Quota{Category: name, Count: counts[i]})
+		}
+	}
+	return quotas
+}
+
+// Total - お題の数の合計
+func (q Quotas) Total() int {
+	total := 0
+	for _, quota := range q {
+		total += quota.Count
+	}
+	return total
+}
+
+// Categories - 配分に含まれるカテゴリ名
+func (q Quotas) Categories() []string {
+	names := []string{}
+	for _, quota := range q {
+		names = append(names, quota.Category)
+	}
+	return names
+}
+
+// Split - 1回の生成がsize個以下になるように配分を分割（1つのカテゴリが複数の生成に分かれることがある）
+func (q Quotas) Split(size int) []Quotas {
+	var batches []Quotas
+	var current Quotas
+	room := size
+	for _, quota := range q {
+		for remaining := quota.Count; remaining > 0; {
+			n := remaining
+			if n > room {
+				n = room
+			}
+			current = append(current, Quota{Category: quota.Category, Count: n})
+			remaining -= n
+			room -= n
+			if room == 0 {
+				batches = append(batches, current)
+				current = nil
+				room = size
+			}
+		}
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// CategoryText - プロンプトのカテゴリ配分部分を作成
+func CategoryText(quotas Quotas) string {
+	lines := []string{fmt.Sprintf("【必須のカテゴリ配分】%d個の中で以下を必ず含めること。以下以外のカテゴリは出題しないこと：", quotas.Total())}
+	for _, q := range quotas {
+		for _, c := range Categories {
+			if c.Name == q.Category {
+				lines = append(lines, fmt.Sprintf("- %s（%d問）：%s", c.Name, q.Count, c.Examples))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AvoidText - プロンプトの使用済みお題部分を作成（最新100個、使用済みがない場合は空文字）
+func AvoidText(usedTopics []string) string {
+	if len(usedTopics) == 0 {
+		return ""
+	}
+	recentUsed := usedTopics
+	if len(usedTopics) > maxAvoidTopics {
+		recentUsed = usedTopics[len(usedTopics)-maxAvoidTopics:]
+	}
+	return fmt.Sprintf("\n\n【絶対に避けるべきお題】以下と同じ・類似のお題は絶対に出さないこと。似たパターンも禁止：\n%s", strings.Join(recentUsed, "\n"))
+}
+
+// UserPrompt - お題生成のユーザーメッセージ
+func UserPrompt(count int) string {
+	return fmt.Sprintf("上記の条件に従って、高品質なお題を%d個生成してください。各カテゴリからバランスよく出題し、同じパターンの繰り返しを避けてください。", count)
+}
+
+// SystemPrompt - お題生成のシステムプロンプト（高品質プロンプト）
+func SystemPrompt(usedTopics []string, quotas Quotas) string {
+	return fmt.Sprintf(`あなたは「認識合わせゲーム」のお題作成の専門家です。
+このゲームでは、参加者全員が同じ答えを思いつくことが目標です。
+
+【あなたの任務】
+日本人なら誰でも答えが一致するような、高品質なお題を%d個作成してください。
+
+【高品質なお題の条件】
+1. 答えが1〜3個に自然と収束する
+2. 具体的な場面・状況で限定されている
+3. 「〜といえば？」の形式で統一
+4. 日本人の常識・共通体験に基づいている
+
+%s
+
+【良いお題の例】
+- コンビニのおにぎりで一番人気の具といえば？
+- 修学旅行で行く定番の場所といえば？
+- ドラえもんの道具の定番といえば？
+- 給食の人気メニューといえば？
+- 動物園の人気者といえば？
+- 赤い野菜といえば？
+- ファストフードの定番チェーンといえば？
+- 運動会の定番競技といえば？
+
+【絶対にNGな例】
+- 「春といえば？」→ 抽象的すぎて答えが発散
+- 「好きな食べ物は？」→ 個人の好みで答えがバラバラ
+- 「有名アーティストの代表曲は？」→ 二段階で絞っていて答えが定まらない
+- 同じパターンの連続（「黄色い〜」「赤い〜」「青い〜」を連続で出すなど）%s
+
+【出力形式】
+- topicsの各要素に、お題（topic）・カテゴリ名（category）・想定される答え（exampleAnswers）を入れる
+- topicはお題の文のみとし、番号・記号・答えの例は含めない
+- categoryは上記のカテゴリ名をそのまま使う
+- exampleAnswersは多くの人が答えそうな答えを1〜3個、短い単語で入れる
+- 必ず%d個出力すること`, quotas.Total(), CategoryText(quotas), AvoidText(usedTopics), quotas.Total())
+}
+
+// ResponseSchema - お題生成の構造化出力のJSONスキーマ
+// カテゴリは列挙型にして、配分にないカテゴリ名を出力させない
+func ResponseSchema(quotas Quotas) map[string]interface{} {
+	names := quotas.Categories()
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"topics": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"topic":          map[string]interface{}{"type": "string"},
+						"category":       map[string]interface{}{"type": "string", "enum": names},
+						"exampleAnswers": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					},
+					"required":             []string{"topic", "category", "exampleAnswers"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"topics"},
+		"additionalProperties": false,
+	}
+}
+
+// Validate - 生成されたお題を検証して整形（不正な場合はエラー）
+func Validate(topic Topic, categories []string) (Topic, error) {
+	topic.Text = strings.TrimSpace(topic.Text)
+	if topic.Text == "" {
+		return topic, fmt.Errorf("お題が空です")
+	}
+	if strings.ContainsAny(topic.Text, "\n→") {
+		return topic, fmt.Errorf("お題に改行・答えの例が含まれています: %s", topic.Text)
+	}
+	if utf8.RuneCountInString(topic.Text) > MaxTopicLength {
+		return topic, fmt.Errorf("お題が長すぎます: %s", topic.Text)
+	}
+	if !strings.HasSuffix(topic.Text, "？") && !strings.HasSuffix(topic.Text, "?") {
+		return topic, fmt.Errorf("お題が質問の形式ではありません: %s", topic.Text)
+	}
+
+	if !IsCategory(topic.Category) {
+		return topic, fmt.Errorf("不明なカテゴリ: %s（%s）", topic.Category, topic.Text)
+	}
+	if len(categories) > 0 && !slices.Contains(categories, topic.Category) {
+		return topic, fmt.Errorf("指定外のカテゴリ: %s（%s）", topic.Category, topic.Text)
+	}
+
+	answers := []string{}
+	for _, a := range topic.ExampleAnswers {
+		a = strings.TrimSpace(a)
+		if a != "" && !slices.Contains(answers, a) {
+			answers = append(answers, a)
+		}
+	}
+	if len(answers) == 0 || len(answers) > MaxExampleAnswers {
+		return topic, fmt.Errorf("想定回答は1〜%d個必要です: %s", MaxExampleAnswers, topic.Text)
+	}
+	topic.ExampleAnswers = answers
+
+	return topic, nil
+}
+
+// Accept - 生成されたお題を検証し、使用済み・重複を除いたお題と、除外した理由を返す
+func Accept(topics []Topic, categories []string, used map[string]bool) ([]Topic, []error) {
+	var accepted []Topic
+	var rejected []error
+	seen := make(map[string]bool)
+	for _, topic := range topics {
+		topic, err := Validate(topic, categories)
+		if err != nil {
+			rejected = append(rejected, err)
+			continue
+		}
+		if used[topic.Text] || seen[topic.Text] {
+			continue
+		}
+		seen[topic.Text] = true
+		accepted = append(accepted, topic)
+	}
+	return accepted, rejected
+}
+
+// QuotaShortfalls - 配分の半分に満たないカテゴリの警告
+func QuotaShortfalls(topics []Topic, quotas Quotas) []string {
+	counts := make(map[string]int)
+	for _, t := range topics {
+		counts[t.Category]++
+	}
+
+	var warnings []string
+	for _, q := range quotas {
+		if counts[q.Category]*2 < q.Count {
+			warnings = append(warnings, fmt.Sprintf("カテゴリ「%s」のお題が少なすぎます（%d/%d）", q.Category, counts[q.Category], q.Count))
+		}
+	}
+	return warnings
+}
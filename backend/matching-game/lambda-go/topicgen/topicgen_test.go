@@ -0,0 +1,85 @@
+package topicgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Topic{Text: "  コンビニのおにぎりの具といえば？ ", Category: "食べ物・飲み物", ExampleAnswers: []string{" ツナマヨ ", "鮭", "ツナマヨ", ""}}
+
+	tests := []struct {
+		name       string
+		topic      Topic
+		categories []string
+		wantErr    string
+	}{
+		{"有効なお題", valid, nil, ""},
+		{"カテゴリの指定内", valid, []string{"食べ物・飲み物"}, ""},
+		{"空のお題", Topic{Text: "  ", Category: "その他", ExampleAnswers: []string{"a"}}, nil, "お題が空です"},
+		{"改行を含む", Topic{Text: "朝ごはん\nといえば？", Category: "その他", ExampleAnswers: []string{"a"}}, nil, "改行"},
+		{"答えの例を含む", Topic{Text: "朝ごはん→パン？", Category: "その他", ExampleAnswers: []string{"a"}}, nil, "改行"},
+		{"長すぎる", Topic{Text: strings.Repeat("あ", MaxTopicLength) + "？", Category: "その他", ExampleAnswers: []string{"a"}}, nil, "長すぎます"},
+		{"質問の形式でない", Topic{Text: "朝ごはんといえば", Category: "その他", ExampleAnswers: []string{"a"}}, nil, "質問の形式"},
+		{"半角の疑問符", Topic{Text: "朝ごはんといえば?", Category: "その他", ExampleAnswers: []string{"a"}}, nil, ""},
+		{"不明なカテゴリ", Topic{Text: "朝ごはんといえば？", Category: "天気", ExampleAnswers: []string{"a"}}, nil, "不明なカテゴリ"},
+		{"指定外のカテゴリ", valid, []string{"学校・行事"}, "指定外のカテゴリ"},
+		{"想定回答なし", Topic{Text: "朝ごはんといえば？", Category: "その他", ExampleAnswers: []string{" ", ""}}, nil, "想定回答"},
+		{"想定回答が多すぎる", Topic{Text: "朝ごはんといえば？", Category: "その他", ExampleAnswers: []string{"a", "b", "c", "d"}}, nil, "想定回答"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(tt.topic, tt.categories)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("エラーになった: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q を含むエラー", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizes(t *testing.T) {
+	got, err := Validate(Topic{Text: "  コンビニのおにぎりの具といえば？ ", Category: "食べ物・飲み物", ExampleAnswers: []string{" ツナマヨ ", "鮭", "ツナマヨ", ""}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "コンビニのおにぎりの具といえば？" {
+		t.Errorf("Text = %q", got.Text)
+	}
+	if want := []string{"ツナマヨ", "鮭"}; !reflect.DeepEqual(got.ExampleAnswers, want) {
+		t.Errorf("ExampleAnswers = %v, want %v", got.ExampleAnswers, want)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	topics := []Topic{
+		{Text: "給食の人気メニューといえば？", Category: "食べ物・飲み物", ExampleAnswers: []string{"カレー"}},
+		{Text: "給食の人気メニューといえば？ ", Category: "食べ物・飲み物", ExampleAnswers: []string{"揚げパン"}}, // 整形後に重複
+		{Text: "夏休みの宿題といえば？", Category: "学校・行事", ExampleAnswers: []string{"読書感想文"}},      // 使用済み
+		{Text: "修学旅行の行き先といえば", Category: "場所・観光地", ExampleAnswers: []string{"京都"}},       // 質問の形式でない
+		{Text: "朝の飲み物といえば？", Category: "食べ物・飲み物", ExampleAnswers: []string{"コーヒー"}},
+	}
+	used := map[string]bool{"夏休みの宿題といえば？": true}
+
+	accepted, rejected := Accept(topics, nil, used)
+
+	var texts []string
+	for _, t := range accepted {
+		texts = append(texts, t.Text)
+	}
+	if want := []string{"給食の人気メニューといえば？", "朝の飲み物といえば？"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("採用 = %v, want %v", texts, want)
+	}
+	if len(rejected) != 1 {
+		t.Errorf("不正 = %v, want 1件", rejected)
+	}
+	if used["朝の飲み物といえば？"] {
+		t.Error("Acceptが使用済みのマップを書き換えた")
+	}
+}
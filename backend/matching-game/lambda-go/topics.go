@@ -0,0 +1,64 @@
+// topics.go - お題プールの保存形式（カテゴリ・想定回答付き）とルーム更新の共通処理
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// UnmarshalDynamoDBAttributeValue - お題プールの要素を読み込む
+// カテゴリ導入前のルームはお題の文字列だけを保存しているため、文字列の場合はカテゴリなしのお題として扱う
+func (t *Topic) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	if s, ok := av.(*types.AttributeValueMemberS); ok {
+		*t = Topic{Text: s.Value, ExampleAnswers: []string{}}
+		return nil
+	}
+
+	// Topic型のまま読み込むとこのメソッドが再帰的に呼ばれるため、別の型を経由する
+	type topicItem Topic
+	var item topicItem
+	if err := attributevalue.Unmarshal(av, &item); err != nil {
+		return err
+	}
+	*t = Topic(item)
+	if t.ExampleAnswers == nil {
+		t.ExampleAnswers = []string{}
+	}
+	return nil
+}
+
+// marshalTopicList - お題プールをDynamoDB用の属性値に変換
+func marshalTopicList(list []Topic) (types.AttributeValue, error) {
+	if len(list) == 0 {
+		return &types.AttributeValueMemberL{Value: []types.AttributeValue{}}, nil
+	}
+	av, err := attributevalue.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("お題のマーシャルに失敗: %w", err)
+	}
+	return av, nil
+}
+
+// topicTexts - お題の文のみのリスト
+func topicTexts(list []Topic) []string {
+	texts := []string{}
+	for _, t := range list {
+		texts = append(texts, t.Text)
+	}
+	return texts
+}
+
+// topicUpdate - UpdateItemの式に現在のお題とカテゴリの更新を追加
+// カテゴリがある場合はSET句の断片に含め、ない場合（旧データのお題）はREMOVE句に加える属性名を返す
+func topicUpdate(topic Topic, names map[string]string, values map[string]types.AttributeValue) (setClause, removeClause string) {
+	names["#topic"] = "topic"
+	names["#topicCategory"] = "topicCategory"
+	values[":topic"] = &types.AttributeValueMemberS{Value: topic.Text}
+	if topic.Category == "" {
+		return "#topic = :topic", "#topicCategory"
+	}
+	values[":topicCategory"] = &types.AttributeValueMemberS{Value: topic.Category}
+	return "#topic = :topic, #topicCategory = :topicCategory", ""
+}
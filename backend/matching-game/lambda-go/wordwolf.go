@@ -0,0 +1,458 @@
+// wordwolf.go - ワードウルフ（少数派だけ異なるお題を配り、議論と投票で少数派を見つける）
+// ルーム・プレイヤーの管理は認識合わせと共通で、gameTypeがWORDWOLFのルームでのみ使用する
+// 状態はWAITING → DISCUSSING（議論）→ VOTING（投票）→ REVEALED（結果公開）と進み、startGameで次のお題に進む
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// minWordWolfPlayers - ワードウルフの開始に必要な最小人数（観戦者を除く）
+const minWordWolfPlayers = 3
+
+// wordWolfGame - ワードウルフ
+type wordWolfGame struct{}
+
+// Start - お題の組を配ってDISCUSSINGにする
+func (wordWolfGame) Start(ctx context.Context, svc *GameServices, room *Room) (*Room, error) {
+	return startWordWolf(ctx, svc, room)
+}
+
+// IsRoundInProgress - 議論・投票中か（結果公開後は次のラウンドを待つ状態）
+func (wordWolfGame) IsRoundInProgress(state string) bool {
+	return state == "DISCUSSING" || state == "VOTING"
+}
+
+// Resolvers - お題の取得・投票・結果公開のフィールド
+func (wordWolfGame) Resolvers() map[string]resolverFunc {
+	return map[string]resolverFunc{
+		"getMyWord":      resolver(getMyWord),
+		"startVoting":    resolver(startVoting),
+		"submitVote":     answerResolver("WORDWOLF", true),
+		"revealWordWolf": judgeResolver("WORDWOLF", requireVoting),
+	}
+}
+
+// startWordWolf - ワードウルフのお題を配って議論を開始（startGameから呼ばれる）
+// 結果公開後に呼んだ場合は次のラウンドとして続行し、それ以外は1ラウンド目から始める
+func startWordWolf(ctx context.Context, svc *GameServices, room *Room) (*Room, error) {
+	if room.State == "DISCUSSING" || room.State == "VOTING" {
+		return nil, fmt.Errorf("ワードウルフの進行中は開始できません")
+	}
+
+	continuing := room.State == "REVEALED"
+	round := 1
+	if continuing {
+		if room.Settings.MaxRounds > 0 && room.Round >= room.Settings.MaxRounds {
+			return nil, fmt.Errorf("設定されたラウンド数（%d）に達しました。ゲームを終了してください", room.Settings.MaxRounds)
+		}
+		round = room.Round + 1
+	}
+
+	// 観戦者以外の全員が参加者（途中参加で待機していたプレイヤーも含める）
+	participantIDs := []string{}
+	for _, p := range room.Players {
+		if p.Role != "SPECTATOR" {
+			participantIDs = append(participantIDs, p.PlayerID)
+		}
+	}
+	if len(participantIDs) < minWordWolfPlayers {
+		return nil, fmt.Errorf("ワードウルフは%d人以上で開始してください", minWordWolfPlayers)
+	}
+	// 少数派が多数派より少なくなる人数が必要
+	if len(participantIDs) <= room.Settings.WolfCount*2 {
+		return nil, fmt.Errorf("少数派%d人でプレイするには%d人以上必要です", room.Settings.WolfCount, room.Settings.WolfCount*2+1)
+	}
+
+	log.Println("お題の組を生成中...")
+	majorityWord, minorityWord, err := svc.WordPair(ctx, room.UsedWordPairs, room.Settings.TopicCategories)
+	if err != nil {
+		return nil, fmt.Errorf("お題の生成に失敗: %w", err)
+	}
+	// 生成順に偏りが出ないよう、どちらを少数派にするかもランダムに決める
+	if rand.Intn(2) == 0 {
+		majorityWord, minorityWord = minorityWord, majorityWord
+	}
+	log.Printf("お題の組: majority=%s, minority=%s", majorityWord, minorityWord)
+
+	shuffled := make([]string, len(participantIDs))
+	copy(shuffled, participantIDs)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	wolfIDs := shuffled[:room.Settings.WolfCount]
+
+	now := time.Now().UTC()
+	game := WordWolfGame{
+		MajorityWord:   majorityWord,
+		MinorityWord:   minorityWord,
+		WolfIDs:        wolfIDs,
+		Votes:          map[string]string{},
+		ParticipantIDs: participantIDs,
+	}
+	if room.Settings.DiscussionTime > 0 {
+		endsAt := now.Add(time.Duration(room.Settings.DiscussionTime) * time.Second).Format(time.RFC3339)
+		game.DiscussionEndsAt = &endsAt
+	}
+
+	gameItem, err := attributevalue.Marshal(game)
+	if err != nil {
+		return nil, fmt.Errorf("ワードウルフのマーシャルに失敗: %w", err)
+	}
+
+	// 組の並びから少数派のお題がわかるため、クライアントに返すusedTopicsではなく非公開のusedWordPairsに記録する
+	usedWordPairs := append(room.UsedWordPairs, majorityWord+"/"+minorityWord)
+
+	err = svc.UpdateRoom(ctx, room.RoomID, RoomUpdate{
+		Expression: "SET #state = :state, #wordWolf = :wordWolf, #usedWordPairs = :usedWordPairs, #round = :round, #updatedAt = :updatedAt REMOVE #topic, #answerDeadline",
+		Names: map[string]string{
+			"#state":          "state",
+			"#wordWolf":       "wordWolf",
+			"#usedWordPairs":  "usedWordPairs",
+			"#round":          "round",
+			"#updatedAt":      "updatedAt",
+			"#topic":          "topic",
+			"#answerDeadline": "answerDeadline",
+		},
+		Values: map[string]types.AttributeValue{
+			":state":         &types.AttributeValueMemberS{Value: "DISCUSSING"},
+			":wordWolf":      gameItem,
+			":usedWordPairs": marshalStringList(usedWordPairs),
+			":round":         &types.AttributeValueMemberN{Value: strconv.Itoa(round)},
+			":updatedAt":     &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
+	}
+
+	// 前のラウンドの途中に参加したプレイヤーもこのラウンドから参加している
+	activateWaitingPlayers(ctx, room.Players)
+	if !continuing {
+		resetReady(ctx, room.Players)
+		resetPlayerScores(ctx, room.Players)
+	}
+
+	log.Printf("ワードウルフ開始: roomId=%s, round=%d, participants=%d, wolves=%d", room.RoomID, round, len(participantIDs), len(wolfIDs))
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := svc.GetRoom(ctx, room.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedRoom, nil
+}
+
+// getMyWord - 自分に配られたお題を取得（参加者本人のみ）
+// 少数派かどうかは本人にも知らせない
+func getMyWord(ctx context.Context, args map[string]interface{}) (*MyWord, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := requireGameType(room, "WORDWOLF"); err != nil {
+		return nil, err
+	}
+	if room.WordWolf == nil {
+		return nil, fmt.Errorf("ワードウルフが開始されていません")
+	}
+	if !slices.Contains(room.WordWolf.ParticipantIDs, playerID) {
+		return nil, fmt.Errorf("このラウンドの参加者ではありません")
+	}
+	if _, err := requirePlayerCaller(ctx, room, playerID); err != nil {
+		return nil, err
+	}
+
+	word := room.WordWolf.MajorityWord
+	if slices.Contains(room.WordWolf.WolfIDs, playerID) {
+		word = room.WordWolf.MinorityWord
+	}
+
+	return &MyWord{
+		RoomID:   roomID,
+		PlayerID: playerID,
+		Word:     word,
+	}, nil
+}
+
+// startVoting - 議論を終えて投票に進む
+// ホスト・共同ホストはいつでも、参加者は議論時間を過ぎた後に進められる
+func startVoting(ctx context.Context, args map[string]interface{}) (*Room, error) {
+	roomID := args["roomId"].(string)
+	playerID := args["playerId"].(string)
+
+	room, err := getRoomItem(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, fmt.Errorf("ルームが見つかりません")
+	}
+	if err := requireGameType(room, "WORDWOLF"); err != nil {
+		return nil, err
+	}
+	if room.State != "DISCUSSING" || room.WordWolf == nil {
+		return nil, fmt.Errorf("議論中ではありません")
+	}
+	if _, err := requirePlayerCaller(ctx, room, playerID); err != nil {
+		return nil, err
+	}
+
+	timeUp := room.WordWolf.DiscussionEndsAt != nil && isPastDeadline(room.WordWolf.DiscussionEndsAt)
+	if !timeUp || !slices.Contains(room.WordWolf.ParticipantIDs, playerID) {
+		if err := requireGameControl(ctx, room, playerID); err != nil {
+			return nil, fmt.Errorf("議論時間が終わるまで投票に進めません")
+		}
+	}
+
+	// 複数の参加者が同時に進めても1回だけ遷移させる
+	_, err = ddbClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(roomTable),
+		Key: map[string]types.AttributeValue{
+			"roomId": &types.AttributeValueMemberS{Value: roomID},
+		},
+		UpdateExpression:    aws.String("SET #state = :voting, #updatedAt = :updatedAt"),
+		ConditionExpression: aws.String("#state = :discussing"),
+		ExpressionAttributeNames: map[string]string{
+			"#state":     "state",
+			"#updatedAt": "updatedAt",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":voting":     &types.AttributeValueMemberS{Value: "VOTING"},
+			":discussing": &types.AttributeValueMemberS{Value: "DISCUSSING"},
+			":updatedAt":  &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &condErr) {
+			return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
+		}
+		log.Printf("既に投票に進んでいます: roomId=%s", roomID)
+	}
+
+	// 更新後のルーム情報を取得して返す
+	updatedRoom, err := getRoom(ctx, map[string]interface{}{"roomId": roomID})
+	if err != nil {
+		return nil, err
+	}
+
+	return updatedRoom, nil
+}
+
+// SubmitAnswer - 少数派だと思うプレイヤーに投票（submitVote、公開前なら投票し直せる）
+// ルームに残っている参加者全員が投票した時点で自動的に結果を公開する
+func (g wordWolfGame) SubmitAnswer(ctx context.Context, svc *GameServices, room *Room, player *Player, args map[string]interface{}) (interface{}, error) {
+	playerID := player.PlayerID
+	targetPlayerID := args["targetPlayerId"].(string)
+
+	if room.State != "VOTING" || room.WordWolf == nil {
+		return nil, fmt.Errorf("投票中ではありません")
+	}
+	if !slices.Contains(room.WordWolf.ParticipantIDs, playerID) {
+		return nil, fmt.Errorf("このラウンドの参加者ではありません")
+	}
+	if !slices.Contains(room.WordWolf.ParticipantIDs, targetPlayerID) {
+		return nil, fmt.Errorf("投票先のプレイヤーが見つかりません")
+	}
+	if targetPlayerID == playerID {
+		return nil, fmt.Errorf("自分には投票できません")
+	}
+
+	err := svc.UpdateRoom(ctx, room.RoomID, RoomUpdate{
+		Expression: "SET #wordWolf.#votes.#voter = :target, #updatedAt = :updatedAt",
+		Condition:  "#state = :voting",
+		Names: map[string]string{
+			"#wordWolf":  "wordWolf",
+			"#votes":     "votes",
+			"#voter":     playerID,
+			"#state":     "state",
+			"#updatedAt": "updatedAt",
+		},
+		Values: map[string]types.AttributeValue{
+			":target":    &types.AttributeValueMemberS{Value: targetPlayerID},
+			":voting":    &types.AttributeValueMemberS{Value: "VOTING"},
+			":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil, fmt.Errorf("投票は締め切られました")
+		}
+		return nil, fmt.Errorf("投票の保存に失敗: %w", err)
+	}
+
+	log.Printf("投票: roomId=%s, playerId=%s", room.RoomID, playerID)
+
+	updatedRoom, err := svc.GetRoom(ctx, room.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 退出したプレイヤーを待たないよう、ルームに残っている参加者だけで判定する
+	if allVoted(updatedRoom) {
+		revealed, err := g.Judge(ctx, svc, updatedRoom, args)
+		if err != nil {
+			// 別のリクエストが先に公開した場合など
+			log.Printf("警告: 結果の自動公開に失敗: %v", err)
+			return svc.GetRoom(ctx, room.RoomID)
+		}
+		return revealed, nil
+	}
+
+	return updatedRoom, nil
+}
+
+// requireVoting - 投票中か確認（revealWordWolf）
+func requireVoting(room *Room) error {
+	if room.State != "VOTING" || room.WordWolf == nil {
+		return fmt.Errorf("投票中ではありません")
+	}
+	return nil
+}
+
+// BuildResult - 得票を集計して結果を作成
+// 最多得票が1人だけで、それが少数派だった場合のみ多数派の勝利とする
+func (wordWolfGame) BuildResult(room *Room, args map[string]interface{}) (interface{}, error) {
+	if err := requireVoting(room); err != nil {
+		return nil, err
+	}
+
+	game := room.WordWolf
+	tally, executedIDs := tallyVotes(game)
+	return &WordWolfResult{
+		MajorityWord: game.MajorityWord,
+		MinorityWord: game.MinorityWord,
+		WolfIDs:      game.WolfIDs,
+		Tally:        tally,
+		ExecutedIDs:  executedIDs,
+		MajorityWins: len(executedIDs) == 1 && slices.Contains(game.WolfIDs, executedIDs[0]),
+	}, nil
+}
+
+// Judge - 投票を締め切って結果を公開し、勝った側の参加者に1点ずつ加算（revealWordWolf）
+func (g wordWolfGame) Judge(ctx context.Context, svc *GameServices, room *Room, args map[string]interface{}) (interface{}, error) {
+	built, err := g.BuildResult(room, args)
+	if err != nil {
+		return nil, err
+	}
+	result := built.(*WordWolfResult)
+	result.RevealedAt = time.Now().UTC().Format(time.RFC3339)
+
+	resultItem, err := attributevalue.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("結果のマーシャルに失敗: %w", err)
+	}
+
+	// 同時に公開された場合も得点が二重に加算されないよう、状態を条件にする
+	err = svc.UpdateRoom(ctx, room.RoomID, RoomUpdate{
+		Expression: "SET #state = :revealed, #wordWolf.#result = :result, #updatedAt = :updatedAt",
+		Condition:  "#state = :voting",
+		Names: map[string]string{
+			"#state":     "state",
+			"#wordWolf":  "wordWolf",
+			"#result":    "result",
+			"#updatedAt": "updatedAt",
+		},
+		Values: map[string]types.AttributeValue{
+			":revealed":  &types.AttributeValueMemberS{Value: "REVEALED"},
+			":voting":    &types.AttributeValueMemberS{Value: "VOTING"},
+			":result":    resultItem,
+			":updatedAt": &types.AttributeValueMemberS{Value: result.RevealedAt},
+		},
+	})
+	if err != nil {
+		var condErr *types.ConditionalCheckFailedException
+		if errors.As(err, &condErr) {
+			return nil, fmt.Errorf("結果は既に公開されています")
+		}
+		return nil, fmt.Errorf("ルームの更新に失敗: %w", err)
+	}
+
+	game := room.WordWolf
+	for _, id := range game.ParticipantIDs {
+		if slices.Contains(game.WolfIDs, id) == result.MajorityWins {
+			continue
+		}
+		if err := svc.AddPlayerScore(ctx, id, 1); err != nil {
+			log.Printf("警告: プレイヤーの得点更新に失敗 %s: %v", id, err)
+		}
+	}
+
+	log.Printf("ワードウルフ結果公開: roomId=%s, executed=%v, majorityWins=%v", room.RoomID, result.ExecutedIDs, result.MajorityWins)
+
+	// 更新後のルーム情報を取得して返す
+	return svc.GetRoom(ctx, room.RoomID)
+}
+
+// allVoted - ルームに残っている参加者全員が投票したか
+func allVoted(room *Room) bool {
+	if room.State != "VOTING" || room.WordWolf == nil {
+		return false
+	}
+	for _, p := range room.Players {
+		if !slices.Contains(room.WordWolf.ParticipantIDs, p.PlayerID) {
+			continue
+		}
+		if _, ok := room.WordWolf.Votes[p.PlayerID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tallyVotes - 参加者ごとの得票数（多い順、同数は参加順）と最多得票のプレイヤーID
+// 誰も投票していない場合、最多得票のプレイヤーはいない
+func tallyVotes(game *WordWolfGame) ([]VoteTally, []string) {
+	counts := make(map[string]int)
+	for _, target := range game.Votes {
+		counts[target]++
+	}
+
+	tally := []VoteTally{}
+	for _, id := range game.ParticipantIDs {
+		tally = append(tally, VoteTally{PlayerID: id, Votes: counts[id]})
+	}
+	sort.SliceStable(tally, func(i, j int) bool { return tally[i].Votes > tally[j].Votes })
+
+	executedIDs := []string{}
+	for _, t := range tally {
+		if t.Votes == 0 || t.Votes < tally[0].Votes {
+			break
+		}
+		executedIDs = append(executedIDs, t.PlayerID)
+	}
+	return tally, executedIDs
+}
+
+// normalizeWordWolf - DBから読み込んだワードウルフの欠損値を補完し、投票済みのプレイヤーを設定
+func normalizeWordWolf(game *WordWolfGame) {
+	if game.ParticipantIDs == nil {
+		game.ParticipantIDs = []string{}
+	}
+	game.VotedPlayerIDs = []string{}
+	for _, id := range game.ParticipantIDs {
+		if _, ok := game.Votes[id]; ok {
+			game.VotedPlayerIDs = append(game.VotedPlayerIDs, id)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTallyVotes(t *testing.T) {
+	tests := []struct {
+		name         string
+		game         WordWolfGame
+		wantTally    []VoteTally
+		wantExecuted []string
+	}{
+		{
+			name: "最多得票が1人",
+			game: WordWolfGame{
+				ParticipantIDs: []string{"a", "b", "c", "d"},
+				Votes:          map[string]string{"a": "c", "b": "c", "c": "a", "d": "c"},
+			},
+			wantTally:    []VoteTally{{"c", 3}, {"a", 1}, {"b", 0}, {"d", 0}},
+			wantExecuted: []string{"c"},
+		},
+		{
+			name: "同票は参加者の順で全員処刑",
+			game: WordWolfGame{
+				ParticipantIDs: []string{"a", "b", "c", "d"},
+				Votes:          map[string]string{"a": "d", "b": "b", "c": "b", "d": "d"},
+			},
+			wantTally:    []VoteTally{{"b", 2}, {"d", 2}, {"a", 0}, {"c", 0}},
+			wantExecuted: []string{"b", "d"},
+		},
+		{
+			name: "投票なし",
+			game: WordWolfGame{
+				ParticipantIDs: []string{"a", "b"},
+			},
+			wantTally:    []VoteTally{{"a", 0}, {"b", 0}},
+			wantExecuted: []string{},
+		},
+		{
+			name: "参加者以外への投票は集計しない",
+			game: WordWolfGame{
+				ParticipantIDs: []string{"a", "b"},
+				Votes:          map[string]string{"a": "x", "b": "a"},
+			},
+			wantTally:    []VoteTally{{"a", 1}, {"b", 0}},
+			wantExecuted: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tally, executed := tallyVotes(&tt.game)
+			if !reflect.DeepEqual(tally, tt.wantTally) {
+				t.Errorf("tally = %v, want %v", tally, tt.wantTally)
+			}
+			if !reflect.DeepEqual(executed, tt.wantExecuted) {
+				t.Errorf("executed = %v, want %v", executed, tt.wantExecuted)
+			}
+		})
+	}
+}
@@ -110,7 +110,7 @@ function MatchingGameApp({ onBack }) {
       const sessionData = {
         roomId: player.roomId,
         playerId: player.playerId,
-        playerName: playerName,
+        playerName: player.name, // 重複時はサーバー側で番号が付くため返却された名前を使う
         isHost: false
       }
       setMultiplayerData(sessionData)
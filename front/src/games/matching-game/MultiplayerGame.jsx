@@ -2,7 +2,7 @@ import { useState, useEffect, useRef } from 'react'
 import { generateClient } from 'aws-amplify/api'
 import NicoComments from './NicoComments'
 import { GET_ROOM, ON_ROOM_UPDATED, ON_PLAYER_JOINED, ON_ANSWER_SUBMITTED, ON_JUDGE_RESULT } from './graphql/queries'
-import { SUBMIT_ANSWER, START_JUDGING, GENERATE_JUDGING_COMMENTS, JUDGE_ANSWERS, START_GAME, NEXT_ROUND, SKIP_TOPIC, END_GAME, LEAVE_ROOM, KICK_PLAYER, DELETE_ALL_DATA } from './graphql/mutations'
+import { SUBMIT_ANSWER, START_JUDGING, GENERATE_JUDGING_COMMENTS, JUDGE_ANSWERS, START_GAME, NEXT_ROUND, SKIP_TOPIC, END_GAME, LEAVE_ROOM, KICK_PLAYER } from './graphql/mutations'
 import './MultiplayerGame.css'
 
 const POLLING_INTERVAL = 30000 // 30秒ごとにポーリング（Subscriptionのフォールバック用）
@@ -320,7 +320,7 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
       console.log('Judging answers:', { roomId, isMatch })
       const result = await client.graphql({
         query: JUDGE_ANSWERS,
-        variables: { roomId, isMatch }
+        variables: { roomId, playerId, isMatch }
       })
       console.log('Judge result:', result)
       console.log('Judge result data:', result.data.judgeAnswers)
@@ -345,7 +345,7 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
       // バックエンドで次のお題を取得してラウンド開始
       await client.graphql({
         query: NEXT_ROUND,
-        variables: { roomId }
+        variables: { roomId, playerId }
       })
       await fetchRoom()
     } catch (err) {
@@ -365,7 +365,7 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
     try {
       await client.graphql({
         query: SKIP_TOPIC,
-        variables: { roomId }
+        variables: { roomId, playerId }
       })
       await fetchRoom()
     } catch (err) {
@@ -380,7 +380,7 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
     try {
       await client.graphql({
         query: END_GAME,
-        variables: { roomId }
+        variables: { roomId, playerId }
       })
       await fetchRoom()
     } catch (err) {
@@ -440,44 +440,15 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
     }))
   }
 
-  const deleteAllData = async () => {
-    if (!confirm('本当に全てのデータを削除しますか？この操作は取り消せません。')) {
-      return
-    }
-
-    setLoading(true)
-    try {
-      console.log('Calling deleteAllData mutation...')
-      const result = await client.graphql({
-        query: DELETE_ALL_DATA
-      })
-      console.log('deleteAllData result:', result)
-      alert(`削除完了:\nルーム: ${result.data.deleteAllData.deletedCounts.rooms}件\nプレイヤー: ${result.data.deleteAllData.deletedCounts.players}件\n回答: ${result.data.deleteAllData.deletedCounts.answers}件`)
-      // ホーム画面に戻る
-      onLeave()
-    } catch (err) {
-      console.error('deleteAllData error:', err)
-      if (err.errors) {
-        console.error('GraphQL errors:', err.errors)
-        err.errors.forEach((error, index) => {
-          console.error(`Error ${index + 1}:`, error.message, error)
-        })
-        setError(`データ削除に失敗しました: ${err.errors[0].message}`)
-      } else {
-        setError('データ削除に失敗しました')
-      }
-    } finally {
-      setLoading(false)
-    }
-  }
-
   if (!room) {
     return <div className="loading">ルーム情報を読み込み中...</div>
   }
 
   const mySubmittedAnswer = room.answers?.find(a => a.playerId === playerId)
-  const allAnswered = room.players?.length > 0 &&
-                     room.answers?.length === room.players?.length
+  // 観戦者・次のラウンド待ちのプレイヤーは回答しないため数えない
+  const answeringPlayers = room.players?.filter(p => p.role !== 'SPECTATOR' && !p.waitingForNextRound) || []
+  const allAnswered = answeringPlayers.length > 0 &&
+                     room.answers?.length === answeringPlayers.length
 
   return (
     <div className="multiplayer-game">
@@ -547,18 +518,10 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
                   {room.players?.length < 2 && (
                     <p className="warning">※ 2人以上必要です</p>
                   )}
-                  <button
-                    onClick={deleteAllData}
-                    disabled={loading}
-                    className="black-button"
-                    style={{ backgroundColor: '#dc2626', marginTop: '2rem' }}
-                  >
-                    {loading ? '削除中...' : '全データ削除（開発用）'}
-                  </button>
                   <button
                     onClick={generateMockAnswers}
                     className="black-button"
-                    style={{ backgroundColor: '#9333ea', marginTop: '0.5rem' }}
+                    style={{ backgroundColor: '#9333ea', marginTop: '2rem' }}
                   >
                     30人テスト（開発用）
                   </button>
@@ -653,7 +616,7 @@ function MultiplayerGame({ roomId, playerId, playerName, isHost, onLeave }) {
                           // 判定画面に遷移（即座に画面遷移）
                           await client.graphql({
                             query: START_JUDGING,
-                            variables: { roomId }
+                            variables: { roomId, playerId }
                           })
                           await fetchRoom()
 
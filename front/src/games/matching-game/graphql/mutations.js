@@ -19,6 +19,7 @@ export const JOIN_ROOM = `
       name
       role
       connected
+      waitingForNextRound
       joinedAt
     }
   }
@@ -52,8 +53,8 @@ export const SUBMIT_ANSWER = `
 `
 
 export const START_JUDGING = `
-  mutation StartJudging($roomId: ID!) {
-    startJudging(roomId: $roomId) {
+  mutation StartJudging($roomId: ID!, $playerId: ID!) {
+    startJudging(roomId: $roomId, playerId: $playerId) {
       roomId
       roomCode
       hostId
@@ -72,6 +73,7 @@ export const START_JUDGING = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -107,6 +109,7 @@ export const GENERATE_JUDGING_COMMENTS = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -122,8 +125,8 @@ export const GENERATE_JUDGING_COMMENTS = `
 `
 
 export const JUDGE_ANSWERS = `
-  mutation JudgeAnswers($roomId: ID!, $isMatch: Boolean!) {
-    judgeAnswers(roomId: $roomId, isMatch: $isMatch) {
+  mutation JudgeAnswers($roomId: ID!, $playerId: ID!, $isMatch: Boolean!) {
+    judgeAnswers(roomId: $roomId, playerId: $playerId, isMatch: $isMatch) {
       roomId
       isMatch
       judgedAt
@@ -152,6 +155,7 @@ export const START_GAME = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -167,8 +171,8 @@ export const START_GAME = `
 `
 
 export const NEXT_ROUND = `
-  mutation NextRound($roomId: ID!) {
-    nextRound(roomId: $roomId) {
+  mutation NextRound($roomId: ID!, $playerId: ID!) {
+    nextRound(roomId: $roomId, playerId: $playerId) {
       roomId
       roomCode
       hostId
@@ -187,6 +191,7 @@ export const NEXT_ROUND = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -202,8 +207,8 @@ export const NEXT_ROUND = `
 `
 
 export const SKIP_TOPIC = `
-  mutation SkipTopic($roomId: ID!) {
-    skipTopic(roomId: $roomId) {
+  mutation SkipTopic($roomId: ID!, $playerId: ID!) {
+    skipTopic(roomId: $roomId, playerId: $playerId) {
       roomId
       roomCode
       hostId
@@ -222,6 +227,7 @@ export const SKIP_TOPIC = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -237,8 +243,8 @@ export const SKIP_TOPIC = `
 `
 
 export const END_GAME = `
-  mutation EndGame($roomId: ID!) {
-    endGame(roomId: $roomId) {
+  mutation EndGame($roomId: ID!, $playerId: ID!) {
+    endGame(roomId: $roomId, playerId: $playerId) {
       roomId
       roomCode
       hostId
@@ -257,6 +263,7 @@ export const END_GAME = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -273,7 +280,37 @@ export const END_GAME = `
 
 export const LEAVE_ROOM = `
   mutation LeaveRoom($roomId: ID!, $playerId: ID!) {
-    leaveRoom(roomId: $roomId, playerId: $playerId)
+    leaveRoom(roomId: $roomId, playerId: $playerId) {
+      roomId
+      roomCode
+      hostId
+      state
+      topic
+      topicsPool
+      usedTopics
+      lastJudgeResult
+      judgedAt
+      comments
+      createdAt
+      updatedAt
+      players {
+        playerId
+        roomCode
+        name
+        role
+        connected
+        waitingForNextRound
+      }
+      answers {
+        answerId
+        playerId
+        playerName
+        answerType
+        textAnswer
+        drawingData
+        submittedAt
+      }
+    }
   }
 `
 
@@ -298,6 +335,7 @@ export const KICK_PLAYER = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -311,17 +349,3 @@ export const KICK_PLAYER = `
     }
   }
 `
-
-export const DELETE_ALL_DATA = `
-  mutation DeleteAllData {
-    deleteAllData {
-      success
-      message
-      deletedCounts {
-        rooms
-        players
-        answers
-      }
-    }
-  }
-`
@@ -19,6 +19,7 @@ export const GET_ROOM = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -51,6 +52,7 @@ export const LIST_PLAYERS = `
       name
       role
       connected
+      waitingForNextRound
     }
   }
 `
@@ -91,6 +93,7 @@ export const ON_ROOM_UPDATED = `
         name
         role
         connected
+        waitingForNextRound
       }
       answers {
         answerId
@@ -114,6 +117,7 @@ export const ON_PLAYER_JOINED = `
       name
       role
       connected
+      waitingForNextRound
       joinedAt
     }
   }
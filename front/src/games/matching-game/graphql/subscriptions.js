@@ -19,6 +19,7 @@ export const ON_ROOM_UPDATED = `
         name
         role
         connected
+        waitingForNextRound
         joinedAt
       }
       answers {
@@ -43,6 +44,7 @@ export const ON_PLAYER_JOINED = `
       name
       role
       connected
+      waitingForNextRound
       joinedAt
     }
   }